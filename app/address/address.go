@@ -0,0 +1,178 @@
+package address
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Address types that can be decoded
+const (
+	TypePubKeyHash  = "pubkeyhash"
+	TypeScriptHash  = "scripthash"
+	TypeColdStaking = "coldstaking"
+)
+
+// Networks an address can belong to
+const (
+	NetworkMain = "main"
+	NetworkTest = "test"
+)
+
+// hashLength is the length of a hash160 key or script id
+const hashLength = 20
+
+// checksumLength is the length of the double sha256 checksum
+const checksumLength = 4
+
+// Errors returned when an address fails to decode
+var (
+	ErrUnsupportedCurrency = errors.New("currency is not supported")
+	ErrInvalidLength       = errors.New("invalid address length")
+	ErrInvalidChecksum     = errors.New("invalid address checksum")
+	ErrInvalidVersion      = errors.New("invalid address version for network")
+)
+
+// NetParams are the base58 version bytes used by a coin network
+type NetParams struct {
+	PubKeyHash  byte
+	ScriptHash  byte
+	ColdStaking byte
+}
+
+// CoinParams holds the mainnet and testnet params of a coin
+type CoinParams struct {
+	Main NetParams
+	Test NetParams
+}
+
+// Params are the address params for each supported currency code
+var Params = map[string]CoinParams{
+	"NAV": {
+		Main: NetParams{PubKeyHash: 53, ScriptHash: 85, ColdStaking: 21},
+		Test: NetParams{PubKeyHash: 111, ScriptHash: 196, ColdStaking: 8},
+	},
+}
+
+// Address is a decoded and validated address
+// cold staking addresses carry both the staking and spending key hashes
+type Address struct {
+	Address      string `json:"address"`
+	Currency     string `json:"currency"`
+	Network      string `json:"network"`
+	Type         string `json:"type"`
+	Hash         string `json:"hash,omitempty"`
+	StakingHash  string `json:"stakingHash,omitempty"`
+	SpendingHash string `json:"spendingHash,omitempty"`
+}
+
+// IsSupported reports whether addresses of the currency can be decoded
+func IsSupported(currency string) bool {
+	_, ok := Params[currency]
+	return ok
+}
+
+// Decode decodes the Base58Check address, verifying its checksum
+// and that its version byte belongs to the currency's network
+func Decode(currency string, addr string, testnet bool) (Address, error) {
+
+	coinParams, ok := Params[currency]
+
+	if !ok {
+		return Address{}, ErrUnsupportedCurrency
+	}
+
+	netParams, network := coinParams.Main, NetworkMain
+
+	if testnet {
+		netParams, network = coinParams.Test, NetworkTest
+	}
+
+	payload, version, err := checkDecode(addr)
+
+	if err != nil {
+		return Address{}, err
+	}
+
+	decoded := Address{Address: addr, Currency: currency, Network: network}
+
+	switch {
+
+	case version == netParams.PubKeyHash && len(payload) == hashLength:
+		decoded.Type = TypePubKeyHash
+		decoded.Hash = hex.EncodeToString(payload)
+
+	case version == netParams.ScriptHash && len(payload) == hashLength:
+		decoded.Type = TypeScriptHash
+		decoded.Hash = hex.EncodeToString(payload)
+
+	case version == netParams.ColdStaking && len(payload) == 2*hashLength:
+		decoded.Type = TypeColdStaking
+		decoded.StakingHash = hex.EncodeToString(payload[:hashLength])
+		decoded.SpendingHash = hex.EncodeToString(payload[hashLength:])
+
+	case version == netParams.PubKeyHash || version == netParams.ScriptHash || version == netParams.ColdStaking:
+		return Address{}, ErrInvalidLength
+
+	default:
+		return Address{}, ErrInvalidVersion
+
+	}
+
+	return decoded, nil
+
+}
+
+// Encode builds the Base58Check string for a version byte and payload
+func Encode(version byte, payload []byte) string {
+
+	b := append([]byte{version}, payload...)
+
+	return Base58Encode(append(b, checksum(b)...))
+
+}
+
+// Validate returns a descriptive error if the address is not valid for the currency
+func Validate(currency string, addr string, testnet bool) error {
+
+	_, err := Decode(currency, addr, testnet)
+
+	if err != nil {
+		return fmt.Errorf("invalid %s address %s: %v", currency, addr, err)
+	}
+
+	return nil
+
+}
+
+// checkDecode decodes the Base58Check string and returns the payload and version
+func checkDecode(addr string) ([]byte, byte, error) {
+
+	decoded, err := Base58Decode(addr)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(decoded) < 1+checksumLength {
+		return nil, 0, ErrInvalidLength
+	}
+
+	data := decoded[:len(decoded)-checksumLength]
+
+	if !bytes.Equal(checksum(data), decoded[len(decoded)-checksumLength:]) {
+		return nil, 0, ErrInvalidChecksum
+	}
+
+	return data[1:], data[0], nil
+
+}
+
+// checksum returns the first four bytes of the double sha256 of b
+func checksum(b []byte) []byte {
+	first := sha256.Sum256(b)
+	second := sha256.Sum256(first[:])
+	return second[:checksumLength]
+}
@@ -0,0 +1,158 @@
+package address
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test that known NavCoin mainnet addresses decode
+func Test_Decode_pubKeyHash(t *testing.T) {
+
+	decoded, err := Decode("NAV", "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, TypePubKeyHash, decoded.Type)
+	assert.Equal(t, NetworkMain, decoded.Network)
+	assert.Equal(t, "6ff3331280e25dad16f810dd7f0cb67f11a8a5cc", decoded.Hash)
+
+}
+
+// test that a single changed character fails the checksum
+func Test_Decode_invalidChecksum(t *testing.T) {
+
+	_, err := Decode("NAV", "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8H", false)
+
+	assert.Equal(t, ErrInvalidChecksum, err)
+
+	_, err = Decode("NAV", "Bak7ahbZAA", false)
+
+	assert.Equal(t, ErrInvalidChecksum, err)
+
+}
+
+// test that mainnet addresses are rejected on testnet
+func Test_Decode_wrongNetwork(t *testing.T) {
+
+	_, err := Decode("NAV", "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", true)
+
+	assert.Equal(t, ErrInvalidVersion, err)
+
+}
+
+// test script hash and cold staking addresses built with Encode
+func Test_Decode_scriptHashAndColdStaking(t *testing.T) {
+
+	staking := bytes.Repeat([]byte{0x11}, hashLength)
+	spending := bytes.Repeat([]byte{0x22}, hashLength)
+
+	p2sh := Encode(Params["NAV"].Main.ScriptHash, staking)
+	decoded, err := Decode("NAV", p2sh, false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, TypeScriptHash, decoded.Type)
+
+	coldStaking := Encode(Params["NAV"].Test.ColdStaking, append(staking, spending...))
+	decoded, err = Decode("NAV", coldStaking, true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, TypeColdStaking, decoded.Type)
+	assert.Equal(t, NetworkTest, decoded.Network)
+	assert.Equal(t, "1111111111111111111111111111111111111111", decoded.StakingHash)
+	assert.Equal(t, "2222222222222222222222222222222222222222", decoded.SpendingHash)
+
+	// a cold staking version byte with a single key hash is the wrong length
+	_, err = Decode("NAV", Encode(Params["NAV"].Main.ColdStaking, staking), false)
+
+	assert.Equal(t, ErrInvalidLength, err)
+
+}
+
+// test unsupported currencies and non base58 input
+func Test_Decode_invalidInput(t *testing.T) {
+
+	_, err := Decode("BTC", "1BoatSLRHtKNngkdXEeobR76b53LETtpyT", false)
+
+	assert.Equal(t, ErrUnsupportedCurrency, err)
+
+	_, err = Decode("NAV", "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T80", false)
+
+	assert.Equal(t, ErrInvalidBase58, err)
+
+	_, err = Decode("NAV", "", false)
+
+	assert.Equal(t, ErrInvalidLength, err)
+
+}
+
+// test that leading zero bytes survive a base58 round trip
+func Test_Base58_roundTrip(t *testing.T) {
+
+	b := []byte{0, 0, 1, 2, 3, 255}
+
+	decoded, err := Base58Decode(Base58Encode(b))
+
+	assert.Nil(t, err)
+	assert.Equal(t, b, decoded)
+	assert.Equal(t, "11", Base58Encode([]byte{0, 0}))
+
+}
+
+// coldStakingScriptHex builds a cold staking output script for the key hashes
+func coldStakingScriptHex(staking []byte, spending []byte) string {
+	script := []byte{opCoinStake, opIf, opDup, opHash160, hashLength}
+	script = append(script, staking...)
+	script = append(script, opEqualVerify, opCheckSig, opElse, opDup, opHash160, hashLength)
+	script = append(script, spending...)
+	script = append(script, opEqualVerify, opCheckSig, opEndIf)
+	return hex.EncodeToString(script)
+}
+
+// test that cold staking scripts are recognised and other scripts are not
+func Test_ParseColdStakingScript(t *testing.T) {
+
+	staking := bytes.Repeat([]byte{0x11}, hashLength)
+	spending := bytes.Repeat([]byte{0x22}, hashLength)
+
+	stakingHash, spendingHash, ok := ParseColdStakingScript(coldStakingScriptHex(staking, spending))
+
+	assert.True(t, ok)
+	assert.Equal(t, staking, stakingHash)
+	assert.Equal(t, spending, spendingHash)
+
+	// pay to pubkey hash
+	_, _, ok = ParseColdStakingScript("76a914" + hex.EncodeToString(staking) + "88ac")
+	assert.False(t, ok)
+
+	_, _, ok = ParseColdStakingScript("not hex")
+	assert.False(t, ok)
+
+}
+
+// test that a cold staking address combines the key hashes of two pubkeyhash addresses
+func Test_ColdStakingAddress(t *testing.T) {
+
+	staking := bytes.Repeat([]byte{0x11}, hashLength)
+	spending := bytes.Repeat([]byte{0x22}, hashLength)
+
+	stakingAddr, _ := EncodePubKeyHash("NAV", staking, false)
+	spendingAddr, _ := EncodePubKeyHash("NAV", spending, false)
+
+	coldStaking, err := ColdStakingAddress("NAV", stakingAddr, spendingAddr, false)
+
+	assert.Nil(t, err)
+
+	decoded, err := Decode("NAV", coldStaking, false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, TypeColdStaking, decoded.Type)
+	assert.Equal(t, hex.EncodeToString(staking), decoded.StakingHash)
+	assert.Equal(t, hex.EncodeToString(spending), decoded.SpendingHash)
+
+	_, err = ColdStakingAddress("NAV", coldStaking, spendingAddr, false)
+
+	assert.Equal(t, ErrNotPubKeyHash, err)
+
+}
@@ -0,0 +1,67 @@
+package address
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// base58Alphabet is the bitcoin base58 alphabet used by NavCoin
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// ErrInvalidBase58 is returned when a string contains non-base58 characters
+var ErrInvalidBase58 = errors.New("invalid base58 character")
+
+var bigRadix = big.NewInt(58)
+
+// Base58Decode decodes a base58 string, preserving leading zero bytes
+func Base58Decode(s string) ([]byte, error) {
+
+	n := new(big.Int)
+
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, ErrInvalidBase58
+		}
+		n.Mul(n, bigRadix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+
+	decoded := n.Bytes()
+
+	// every leading '1' is a leading zero byte
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), decoded...), nil
+
+}
+
+// Base58Encode encodes bytes to a base58 string, preserving leading zero bytes
+func Base58Encode(b []byte) string {
+
+	n := new(big.Int).SetBytes(b)
+	mod := new(big.Int)
+
+	encoded := []byte{}
+
+	for n.Sign() > 0 {
+		n.DivMod(n, bigRadix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+
+	for i := 0; i < len(b) && b[i] == 0; i++ {
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	// digits were produced least significant first
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+
+	return string(encoded)
+
+}
@@ -0,0 +1,142 @@
+package address
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+)
+
+// Script opcodes used to recognise output scripts
+const (
+	opDup         = 0x76
+	opHash160     = 0xa9
+	opEqualVerify = 0x88
+	opCheckSig    = 0xac
+	opIf          = 0x63
+	opElse        = 0x67
+	opEndIf       = 0x68
+	opCoinStake   = 0xc6
+)
+
+// ErrNotPubKeyHash is returned when a cold staking address is built from another address type
+var ErrNotPubKeyHash = errors.New("cold staking addresses are built from pubkeyhash addresses")
+
+// coldStakingScriptLength is the length of a cold staking output script
+const coldStakingScriptLength = 54
+
+// ParseColdStakingScript recognises a cold staking output script - OP_COINSTAKE
+// OP_IF <p2pkh staking> OP_ELSE <p2pkh spending> OP_ENDIF - and returns its
+// staking and spending key hashes
+func ParseColdStakingScript(scriptHex string) ([]byte, []byte, bool) {
+
+	script, err := hex.DecodeString(scriptHex)
+
+	if err != nil || len(script) != coldStakingScriptLength {
+		return nil, nil, false
+	}
+
+	if script[0] != opCoinStake || script[1] != opIf || script[27] != opElse || script[53] != opEndIf {
+		return nil, nil, false
+	}
+
+	staking, ok := parsePubKeyHash(script[2:27])
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	spending, ok := parsePubKeyHash(script[28:53])
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	return staking, spending, true
+
+}
+
+// ColdStakingAddress builds the cold staking address delegating the
+// spending address's coins to be staked by the staking address
+func ColdStakingAddress(currency string, staking string, spending string, testnet bool) (string, error) {
+
+	stakingAddr, err := Decode(currency, staking, testnet)
+
+	if err != nil {
+		return "", err
+	}
+
+	spendingAddr, err := Decode(currency, spending, testnet)
+
+	if err != nil {
+		return "", err
+	}
+
+	if stakingAddr.Type != TypePubKeyHash || spendingAddr.Type != TypePubKeyHash {
+		return "", ErrNotPubKeyHash
+	}
+
+	stakingHash, _ := hex.DecodeString(stakingAddr.Hash)
+	spendingHash, _ := hex.DecodeString(spendingAddr.Hash)
+
+	return EncodeColdStaking(currency, stakingHash, spendingHash, testnet)
+
+}
+
+// EncodeColdStaking builds the cold staking address for the key hashes
+func EncodeColdStaking(currency string, stakingHash []byte, spendingHash []byte, testnet bool) (string, error) {
+
+	netParams, err := netParamsFor(currency, testnet)
+
+	if err != nil {
+		return "", err
+	}
+
+	return Encode(netParams.ColdStaking, append(append([]byte{}, stakingHash...), spendingHash...)), nil
+
+}
+
+// EncodePubKeyHash builds the pubkeyhash address for the key hash
+func EncodePubKeyHash(currency string, hash []byte, testnet bool) (string, error) {
+
+	netParams, err := netParamsFor(currency, testnet)
+
+	if err != nil {
+		return "", err
+	}
+
+	return Encode(netParams.PubKeyHash, hash), nil
+
+}
+
+// parsePubKeyHash returns the key hash of a pay to pubkey hash script
+// OP_DUP OP_HASH160 <20 bytes> OP_EQUALVERIFY OP_CHECKSIG
+func parsePubKeyHash(script []byte) ([]byte, bool) {
+
+	if len(script) != 25 || !bytes.Equal(script[:3], []byte{opDup, opHash160, hashLength}) {
+		return nil, false
+	}
+
+	if script[23] != opEqualVerify || script[24] != opCheckSig {
+		return nil, false
+	}
+
+	return script[3:23], true
+
+}
+
+// netParamsFor returns the currency's params for the network
+func netParamsFor(currency string, testnet bool) (NetParams, error) {
+
+	coinParams, ok := Params[currency]
+
+	if !ok {
+		return NetParams{}, ErrUnsupportedCurrency
+	}
+
+	if testnet {
+		return coinParams.Test, nil
+	}
+
+	return coinParams.Main, nil
+
+}
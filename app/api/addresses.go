@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Encrypt-S/kauri-api/app/address"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/gorilla/mux"
+)
+
+// AddressValidation is the result of validating an address
+type AddressValidation struct {
+	Valid   bool             `json:"valid"`
+	Reason  string           `json:"reason,omitempty"`
+	Address *address.Address `json:"address,omitempty"`
+}
+
+// InitAddressHandlers starts the address api handlers
+func InitAddressHandlers(r *mux.Router, prefix string) {
+	nameSpace := "addresses"
+
+	validatePath := RouteBuilder(prefix, nameSpace, "v1", "validate")
+	OpenRouteHandler(validatePath, r, validateAddressHandler())
+
+}
+
+// validateAddressHandler decodes the ?address= for the ?currency= and reports
+// whether it is valid on the network the coin is configured for
+func validateAddressHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appResp := Response{}
+
+		currency := r.URL.Query().Get("currency")
+		addr := r.URL.Query().Get("address")
+
+		if !address.IsSupported(currency) {
+			appResp.Errors = append(appResp.Errors, AppRespErrors.UnsupportedCoin)
+			appResp.Send(w)
+			return
+		}
+
+		decoded, err := address.Decode(currency, addr, useTestNet(currency))
+
+		if err != nil {
+			appResp.Data = AddressValidation{Valid: false, Reason: err.Error()}
+			appResp.Send(w)
+			return
+		}
+
+		appResp.Data = AddressValidation{Valid: true, Address: &decoded}
+		appResp.Send(w)
+
+	})
+}
+
+// useTestNet reports whether the active coin with the currency code runs on testnet
+func useTestNet(currency string) bool {
+	for _, coinData := range conf.ActiveCoins() {
+		if coinData.CurrencyCode == currency {
+			return coinData.UseTestNet
+		}
+	}
+	return false
+}
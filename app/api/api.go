@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
+	"github.com/Encrypt-S/kauri-api/app/conf"
 	"github.com/Encrypt-S/kauri-api/app/middleware"
 	"github.com/gorilla/mux"
 )
@@ -35,6 +37,11 @@ type appErrorsStruct struct {
 	ServerError      errorCode
 	RPCResponseError errorCode
 	JSONDecodeError  errorCode
+	InvalidAddress   errorCode
+	UnsupportedCoin  errorCode
+	Unauthorized     errorCode
+	InvalidRequest   errorCode
+	NotFound         errorCode
 }
 
 // AppRespErrors variable
@@ -48,15 +55,22 @@ func BuildAppErrors() {
 	// Generic errors
 	AppRespErrors.ServerError = errorCode{"SERVER_ERROR", "There was an unexpected error - please try again"}
 	AppRespErrors.InvalidStrength = errorCode{"INVALID_STRENGTH", ""}
+	AppRespErrors.InvalidRequest = errorCode{"INVALID_REQUEST", "The request is not valid"}
+	AppRespErrors.NotFound = errorCode{"NOT_FOUND", "The requested resource was not found"}
 
 	// RPC Errors
 	AppRespErrors.RPCResponseError = errorCode{"RPC_RESPONSE_ERROR", "There was an RPC response error"}
 
 	// Login Errors
 	AppRespErrors.LoginError = errorCode{"LOGIN_ERROR", "Your username and/or password is wrong"}
+	AppRespErrors.Unauthorized = errorCode{"UNAUTHORIZED", "A valid API token is required"}
 
 	// JSON Errors
 	AppRespErrors.JSONDecodeError = errorCode{"JSON_DECODE_ERROR", "Unable to decode JSON"}
+
+	// Address Errors
+	AppRespErrors.InvalidAddress = errorCode{"INVALID_ADDRESS", "The address is not valid for the currency"}
+	AppRespErrors.UnsupportedCoin = errorCode{"UNSUPPORTED_COIN", "The currency is not supported"}
 }
 
 // RouteBuilder takes prefix, namespace, version, and method params :: returns formatted route
@@ -66,14 +80,22 @@ func RouteBuilder(prefix string, namespace string, version string, method string
 	return route
 }
 
+// CoinRouteBuilder takes prefix, namespace, version, coin and method params :: returns formatted route
+// scoped to the coin's lower case currency code eg. /api/explorer/v1/nav/tip
+func CoinRouteBuilder(prefix string, namespace string, version string, coinData conf.CoinData, method string) string {
+	return RouteBuilder(prefix, namespace, version, strings.ToLower(coinData.CurrencyCode)+"/"+method)
+}
+
 // OpenRouteHandler is utilised for unprotected routes (non-JWT)
 func OpenRouteHandler(path string, r *mux.Router, f http.Handler) {
 	r.Handle(path, middleware.Adapt(f, middleware.CORSHandler()))
 }
 
-// func ProtectedRouteHandler(path string, r *mux.Router, f http.Handler, method string) {
-// 	r.Handle(path, middleware.Adapt(f,
-// 		middleware.CORSHandler(),
-// 		middleware.JwtHandler())).
-// 		Methods(method)
-// }
+// ProtectedRouteHandler is utilised for routes that require the API token
+// CORS is applied outermost so preflight requests don't need the token
+func ProtectedRouteHandler(path string, r *mux.Router, f http.Handler, method string) {
+	r.Handle(path, middleware.Adapt(f,
+		middleware.AuthHandler(),
+		middleware.CORSHandler())).
+		Methods(method, http.MethodOptions)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/Encrypt-S/kauri-api/app/conf"
 	"github.com/appleboy/gofight"
 	"github.com/stretchr/testify/assert"
 )
@@ -36,3 +37,42 @@ func Test_RouteBuilder(t *testing.T) {
 	assert.Equal(t, "/api/chain/v1/getuser", route)
 
 }
+
+func Test_CoinRouteBuilder(t *testing.T) {
+
+	coinData := conf.CoinData{CurrencyCode: "NAV"}
+
+	route := CoinRouteBuilder("api", "explorer", "v1", coinData, "block/{id}")
+
+	assert.Equal(t, "/api/explorer/v1/nav/block/{id}", route)
+
+}
+
+// validateAddressHandler test
+func Test_validateAddressHandler(t *testing.T) {
+	BuildAppErrors()
+	r := gofight.New()
+
+	r.GET("/?currency=NAV&address=NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G").
+		Run(validateAddressHandler(), func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+
+			assert.Contains(t, r.Body.String(), `"valid":true`)
+			assert.Contains(t, r.Body.String(), `"type":"pubkeyhash"`)
+
+		})
+
+	r.GET("/?currency=NAV&address=Bak7ahbZAA").
+		Run(validateAddressHandler(), func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+
+			assert.Contains(t, r.Body.String(), `"valid":false`)
+			assert.Contains(t, r.Body.String(), `"reason":"invalid address checksum"`)
+
+		})
+
+	r.GET("/?currency=BTC&address=Bak7ahbZAA").
+		Run(validateAddressHandler(), func(r gofight.HTTPResponse, rq gofight.HTTPRequest) {
+
+			assert.Contains(t, r.Body.String(), `"code":"UNSUPPORTED_COIN"`)
+
+		})
+}
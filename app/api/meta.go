@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/Encrypt-S/kauri-api/app/cache"
 	"github.com/Encrypt-S/kauri-api/app/conf"
 	"github.com/gorilla/mux"
 )
@@ -17,13 +18,16 @@ func InitMetaHandlers(r *mux.Router, prefix string) {
 	metaCoinPath := RouteBuilder(prefix, nameSpace, "v1", "coins")
 	OpenRouteHandler(metaCoinPath, r, coinMetaHandler())
 
+	metaCachePath := RouteBuilder(prefix, nameSpace, "v1", "cache")
+	OpenRouteHandler(metaCachePath, r, cacheMetaHandler())
+
 }
 
 // metaErrorDisplayHandler displays all the application errors to frontend
 func coinMetaHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		appResp := Response{}
-		appResp.Data = conf.AppConf.Coins
+		appResp.Data = conf.ActiveCoins()
 		appResp.Send(w)
 
 	})
@@ -38,3 +42,13 @@ func metaErrorDisplayHandler() http.Handler {
 
 	})
 }
+
+// cacheMetaHandler displays the size and hit/miss stats of the caches
+func cacheMetaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appResp := Response{}
+		appResp.Data = cache.AllStats()
+		appResp.Send(w)
+
+	})
+}
@@ -0,0 +1,210 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// walletFile is the name of the wallet in the daemon's data dir and in archives
+const walletFile = "wallet.dat"
+
+// encryptedMagic starts every encrypted archive, followed by the scrypt salt and GCM nonce
+var encryptedMagic = []byte("KAURIBK1")
+
+// scrypt parameters deriving the archive key from the passphrase
+const (
+	scryptN  = 32768
+	scryptR  = 8
+	scryptP  = 1
+	keyLen   = 32
+	saltLen  = 16
+	nonceLen = 12
+)
+
+// ErrWrongPassphrase is returned when an encrypted archive can't be opened with the passphrase
+var ErrWrongPassphrase = errors.New("backup passphrase is wrong or the archive is corrupt")
+
+// ErrNoWallet is returned when an archive doesn't contain a wallet
+var ErrNoWallet = errors.New("backup does not contain a wallet")
+
+// archiveWallet packs the wallet file into a tar.gz, encrypted if passphrase is set
+func archiveWallet(walletPath string, passphrase string, modTime time.Time) ([]byte, error) {
+
+	wallet, err := ioutil.ReadFile(walletPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+
+	header := &tar.Header{Name: walletFile, Mode: 0600, Size: int64(len(wallet)), ModTime: modTime}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+
+	if _, err := tw.Write(wallet); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+
+	if passphrase == "" {
+		return buf.Bytes(), nil
+	}
+
+	return encrypt(buf.Bytes(), passphrase)
+
+}
+
+// unarchiveWallet returns the wallet packed in the archive, decrypting it if it is encrypted
+func unarchiveWallet(archive []byte, passphrase string) ([]byte, error) {
+
+	if isEncrypted(archive) {
+		decrypted, err := decrypt(archive, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		archive = decrypted
+	}
+
+	gzr, err := gzip.NewReader(bytes.NewReader(archive))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+
+	for {
+
+		header, err := tr.Next()
+
+		if err == io.EOF {
+			return nil, ErrNoWallet
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg && header.Name == walletFile {
+			return ioutil.ReadAll(tr)
+		}
+
+	}
+
+}
+
+// isEncrypted reports whether the archive starts with the encrypted magic
+func isEncrypted(archive []byte) bool {
+	return bytes.HasPrefix(archive, encryptedMagic)
+}
+
+// encrypt seals the data with AES-GCM under a key derived from the passphrase
+func encrypt(data []byte, passphrase string) ([]byte, error) {
+
+	salt := make([]byte, saltLen)
+	nonce := make([]byte, nonceLen)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := append(append(append([]byte{}, encryptedMagic...), salt...), nonce...)
+
+	return gcm.Seal(out, nonce, data, encryptedMagic), nil
+
+}
+
+// decrypt opens data sealed by encrypt
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+
+	headerLen := len(encryptedMagic) + saltLen + nonceLen
+
+	if passphrase == "" || len(data) < headerLen {
+		return nil, ErrWrongPassphrase
+	}
+
+	salt := data[len(encryptedMagic) : len(encryptedMagic)+saltLen]
+	nonce := data[len(encryptedMagic)+saltLen : headerLen]
+
+	gcm, err := newGCM(passphrase, salt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := gcm.Open(nil, nonce, data[headerLen:], encryptedMagic)
+
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return plain, nil
+
+}
+
+// newGCM derives the archive key from the passphrase and salt
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLen)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
+
+// writeFileAtomic writes the data to a temp file beside path and renames it into place
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+
+}
@@ -0,0 +1,340 @@
+package backup
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+)
+
+// DefaultKeep is the number of backups kept per coin when the config doesn't set it
+const DefaultKeep = 10
+
+// stopTimeout is how long the daemon is given to shut down before a restore
+const stopTimeout = 2 * time.Minute
+
+// timeFormat orders backup names by the time they were taken
+const timeFormat = "20060102-150405.000"
+
+// archive extensions, encrypted archives are sealed tar.gz files
+const (
+	extArchive   = ".tar.gz"
+	extEncrypted = ".tar.gz.enc"
+)
+
+// ErrBackupNotFound is returned when no backup of the coin has the requested name
+var ErrBackupNotFound = errors.New("backup not found")
+
+// the manager's daemon controls and the app's path, replaced in tests
+var (
+	stopDaemon  = daemon.Stop
+	startDaemon = daemon.Start
+	appPath     = fs.GetCurrentPath
+)
+
+// Backup is a wallet archive in the backup dir
+type Backup struct {
+	Name      string    `json:"name"`
+	Currency  string    `json:"currency"`
+	Size      int64     `json:"size"`
+	Encrypted bool      `json:"encrypted"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Backups takes, rotates and restores wallet backups kept in dir
+type Backups struct {
+	dir        string
+	keep       int
+	passphrase string
+
+	mu sync.Mutex
+}
+
+// New creates backups kept in dir, keeping the keep most recent of each coin
+// and encrypting them with passphrase if it is set
+func New(dir string, keep int, passphrase string) *Backups {
+
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+
+	return &Backups{dir: dir, keep: keep, passphrase: passphrase}
+
+}
+
+// Create has the coin's daemon back up its wallet, archives it and rotates old backups
+func (b *Backups) Create(coinData conf.CoinData) (Backup, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return Backup{}, err
+	}
+
+	now := time.Now().UTC()
+
+	// the daemon writes its wallet copy to a temp file in the backup dir
+	walletCopy := filepath.Join(b.dir, coinData.CurrencyCode+"-"+walletFile+".tmp")
+	defer os.Remove(walletCopy)
+
+	if err := daemonrpc.CallDaemon(coinData, "backupwallet", []interface{}{walletCopy}, nil); err != nil {
+		return Backup{}, err
+	}
+
+	archive, err := archiveWallet(walletCopy, b.passphrase, now)
+
+	if err != nil {
+		return Backup{}, err
+	}
+
+	ext := extArchive
+	if b.passphrase != "" {
+		ext = extEncrypted
+	}
+
+	name := coinData.CurrencyCode + "-wallet-" + now.Format(timeFormat) + ext
+
+	if err := writeFileAtomic(filepath.Join(b.dir, name), archive, 0600); err != nil {
+		return Backup{}, err
+	}
+
+	if err := b.rotate(coinData.CurrencyCode); err != nil {
+		log.Println("Failed to rotate " + coinData.CurrencyCode + " backups: " + err.Error())
+	}
+
+	return Backup{Name: name, Currency: coinData.CurrencyCode, Size: int64(len(archive)), Encrypted: ext == extEncrypted, CreatedAt: now}, nil
+
+}
+
+// List returns the coin's backups, newest first
+func (b *Backups) List(currency string) ([]Backup, error) {
+
+	files, err := ioutil.ReadDir(b.dir)
+
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []Backup{}
+
+	for _, file := range files {
+
+		backup, ok := parseName(currency, file.Name())
+
+		if !ok || file.IsDir() {
+			continue
+		}
+
+		backup.Size = file.Size()
+		backups = append(backups, backup)
+
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+
+	return backups, nil
+
+}
+
+// Path returns the path of the coin's backup with the name
+func (b *Backups) Path(currency string, name string) (string, error) {
+
+	if _, ok := parseName(currency, name); !ok {
+		return "", ErrBackupNotFound
+	}
+
+	path := filepath.Join(b.dir, name)
+
+	if !fs.Exists(path) {
+		return "", ErrBackupNotFound
+	}
+
+	return path, nil
+
+}
+
+// Restore stops the coin's daemon, swaps its wallet for the one in the backup,
+// keeping the replaced wallet beside it, and starts the daemon again
+func (b *Backups) Restore(coinData conf.CoinData, name string) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path, err := b.Path(coinData.CurrencyCode, name)
+
+	if err != nil {
+		return err
+	}
+
+	archive, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	wallet, err := unarchiveWallet(archive, b.passphrase)
+
+	if err != nil {
+		return err
+	}
+
+	walletPath, err := locateWallet(coinData)
+
+	if err != nil {
+		return err
+	}
+
+	// write the restored wallet beside the current one before touching the daemon
+	restored := walletPath + ".restore"
+
+	if err := ioutil.WriteFile(restored, wallet, 0600); err != nil {
+		return err
+	}
+
+	defer os.Remove(restored)
+
+	if err := stopDaemon(coinData, stopTimeout); err != nil && err != daemon.ErrNotRunning {
+		return err
+	}
+
+	if fs.Exists(walletPath) {
+		replaced := walletPath + "." + time.Now().UTC().Format(timeFormat) + ".bak"
+		if err := os.Rename(walletPath, replaced); err != nil {
+			return restartAfter(coinData, err)
+		}
+	}
+
+	if err := os.Rename(restored, walletPath); err != nil {
+		return restartAfter(coinData, err)
+	}
+
+	log.Println("Restored " + coinData.CurrencyCode + " wallet from " + name)
+
+	if err := startDaemon(coinData); err != nil {
+		return errors.New("the wallet was restored but the " + coinData.CurrencyCode + " daemon did not start again: " + err.Error())
+	}
+
+	return nil
+
+}
+
+// restartAfter starts the coin's daemon again after a failed restore, returning
+// the restore's error along with the daemon's if it did not come back
+func restartAfter(coinData conf.CoinData, err error) error {
+
+	if startErr := startDaemon(coinData); startErr != nil {
+		return errors.New(err.Error() + ", and the " + coinData.CurrencyCode + " daemon did not start again: " + startErr.Error())
+	}
+
+	return err
+
+}
+
+// Run backs up the coins every interval until stop is closed
+func (b *Backups) Run(coins []conf.CoinData, interval time.Duration, stop <-chan struct{}) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, coinData := range coins {
+				if _, err := b.Create(coinData); err != nil {
+					log.Println("Failed to back up the " + coinData.CurrencyCode + " wallet: " + err.Error())
+				}
+			}
+		}
+	}
+
+}
+
+// rotate removes all but the most recent backups of the coin
+func (b *Backups) rotate(currency string) error {
+
+	backups, err := b.List(currency)
+
+	if err != nil {
+		return err
+	}
+
+	for i := b.keep; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(b.dir, backups[i].Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// parseName parses a backup file name of the currency, ignoring any other file
+func parseName(currency string, name string) (Backup, bool) {
+
+	prefix := currency + "-wallet-"
+
+	if !strings.HasPrefix(name, prefix) || strings.ContainsAny(name, `/\`) {
+		return Backup{}, false
+	}
+
+	stamp := strings.TrimPrefix(name, prefix)
+	encrypted := strings.HasSuffix(stamp, extEncrypted)
+
+	switch {
+	case encrypted:
+		stamp = strings.TrimSuffix(stamp, extEncrypted)
+	case strings.HasSuffix(stamp, extArchive):
+		stamp = strings.TrimSuffix(stamp, extArchive)
+	default:
+		return Backup{}, false
+	}
+
+	createdAt, err := time.Parse(timeFormat, stamp)
+
+	if err != nil {
+		return Backup{}, false
+	}
+
+	return Backup{Name: name, Currency: currency, Encrypted: encrypted, CreatedAt: createdAt}, true
+
+}
+
+// locateWallet finds the wallet in the coin's data dir, testnet
+// wallets are kept in the network's sub directory
+func locateWallet(coinData conf.CoinData) (string, error) {
+
+	path, err := appPath()
+
+	if err != nil {
+		return "", err
+	}
+
+	dataDir := path + coinData.DataDir
+
+	if coinData.UseTestNet {
+		matches, _ := filepath.Glob(filepath.Join(dataDir, "testnet*", walletFile))
+		if len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+
+	return filepath.Join(dataDir, walletFile), nil
+
+}
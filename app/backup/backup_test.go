@@ -0,0 +1,211 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+func mockCoinData() conf.CoinData {
+	data := conf.CoinData{}
+	data.CurrencyCode = "NAV"
+	data.DataDir = "/data"
+	return data
+}
+
+// mockBackupWalletResponder writes the wallet to the path requested by 'backupwallet'
+func mockBackupWalletResponder(wallet []byte) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		rpcReq := struct {
+			Params []string `json:"params"`
+		}{}
+		json.NewDecoder(req.Body).Decode(&rpcReq)
+		ioutil.WriteFile(rpcReq.Params[0], wallet, 0600)
+		return httpmock.NewStringResponse(200, `{"result": null, "error": null, "id": null}`), nil
+	}
+}
+
+// test that wallets survive archiving, with and without encryption
+func Test_archiveWallet(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "backup")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	walletPath := filepath.Join(dir, walletFile)
+	ioutil.WriteFile(walletPath, []byte("wallet contents"), 0600)
+
+	archive, err := archiveWallet(walletPath, "", time.Now())
+
+	assert.Nil(t, err)
+	assert.False(t, isEncrypted(archive))
+
+	wallet, err := unarchiveWallet(archive, "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "wallet contents", string(wallet))
+
+	archive, err = archiveWallet(walletPath, "secret", time.Now())
+
+	assert.Nil(t, err)
+	assert.True(t, isEncrypted(archive))
+	assert.NotContains(t, string(archive), "wallet contents")
+
+	wallet, err = unarchiveWallet(archive, "secret")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "wallet contents", string(wallet))
+
+	_, err = unarchiveWallet(archive, "wrong")
+	assert.Equal(t, ErrWrongPassphrase, err)
+
+	_, err = unarchiveWallet(archive, "")
+	assert.Equal(t, ErrWrongPassphrase, err)
+
+}
+
+// test that backups are listed newest first and rotated
+func Test_Create(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", mockBackupWalletResponder([]byte("wallet contents")))
+
+	dir, err := ioutil.TempDir("", "backup")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	backups := New(dir, 2, "secret")
+
+	names := []string{}
+
+	for i := 0; i < 3; i++ {
+		backup, err := backups.Create(mockCoinData())
+		assert.Nil(t, err)
+		assert.True(t, backup.Encrypted)
+		names = append(names, backup.Name)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	list, err := backups.List("NAV")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, names[2], list[0].Name)
+	assert.Equal(t, names[1], list[1].Name)
+
+	// the daemon's wallet copy isn't left behind
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 2, len(files))
+
+	other, err := backups.List("BTC")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(other))
+
+}
+
+// test that only the coin's backups can be resolved to a path
+func Test_Path(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "backup")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := "NAV-wallet-20181019-120000.000.tar.gz"
+	ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0600)
+
+	backups := New(dir, 0, "")
+
+	path, err := backups.Path("NAV", name)
+
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, name), path)
+
+	_, err = backups.Path("BTC", name)
+	assert.Equal(t, ErrBackupNotFound, err)
+
+	_, err = backups.Path("NAV", "NAV-wallet-20181019-120001.000.tar.gz")
+	assert.Equal(t, ErrBackupNotFound, err)
+
+	_, err = backups.Path("NAV", "NAV-wallet-../../wallet.dat")
+	assert.Equal(t, ErrBackupNotFound, err)
+
+}
+
+// test that restoring swaps the wallet while the daemon is stopped
+func Test_Restore(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", mockBackupWalletResponder([]byte("old wallet")))
+
+	dir, err := ioutil.TempDir("", "backup")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dataDir := filepath.Join(dir, "data")
+	os.MkdirAll(dataDir, 0700)
+	ioutil.WriteFile(filepath.Join(dataDir, walletFile), []byte("new wallet"), 0600)
+
+	stop, start, path := stopDaemon, startDaemon, appPath
+	defer func() { stopDaemon, startDaemon, appPath = stop, start, path }()
+
+	events := []string{}
+
+	stopDaemon = func(coinData conf.CoinData, timeout time.Duration) error {
+		events = append(events, "stop")
+		return nil
+	}
+
+	startDaemon = func(coinData conf.CoinData) error {
+		wallet, _ := ioutil.ReadFile(filepath.Join(dataDir, walletFile))
+		events = append(events, "start:"+string(wallet))
+		return nil
+	}
+
+	appPath = func() (string, error) {
+		return dir, nil
+	}
+
+	backups := New(filepath.Join(dir, "backups"), 0, "")
+
+	backup, err := backups.Create(mockCoinData())
+	assert.Nil(t, err)
+
+	err = backups.Restore(mockCoinData(), backup.Name)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stop", "start:old wallet"}, events)
+
+	// the replaced wallet is kept
+	matches, _ := filepath.Glob(filepath.Join(dataDir, walletFile+".*.bak"))
+	assert.Equal(t, 1, len(matches))
+
+	replaced, _ := ioutil.ReadFile(matches[0])
+	assert.Equal(t, "new wallet", string(replaced))
+
+	err = backups.Restore(mockCoinData(), "NAV-wallet-20181019-120000.000.tar.gz")
+	assert.Equal(t, ErrBackupNotFound, err)
+
+	// a daemon that doesn't come back is reported
+	startDaemon = func(coinData conf.CoinData) error {
+		return errors.New("exec: no such file")
+	}
+
+	err = backups.Restore(mockCoinData(), backup.Name)
+
+	assert.EqualError(t, err, "the wallet was restored but the NAV daemon did not start again: exec: no such file")
+
+}
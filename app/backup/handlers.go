@@ -0,0 +1,132 @@
+package backup
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/gorilla/mux"
+)
+
+// InitBackupHandlers sets up the protected endpoints to take, list, download and restore the coin's wallet backups
+func InitBackupHandlers(r *mux.Router, backups *Backups, coinData conf.CoinData, prefix string) {
+
+	namespace := "backup"
+
+	// backups endpoint :: lists the coin's backups, or takes one now
+	backupsPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "backups")
+	api.ProtectedRouteHandler(backupsPath, r, listBackupsHandler(backups, coinData), http.MethodGet)
+	api.ProtectedRouteHandler(backupsPath, r, createBackupHandler(backups, coinData), http.MethodPost)
+
+	// backup endpoint :: downloads the backup archive
+	backupPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "backups/{name}")
+	api.ProtectedRouteHandler(backupPath, r, downloadBackupHandler(backups, coinData), http.MethodGet)
+
+	// restore endpoint :: restores the wallet from the backup, restarting the daemon
+	restorePath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "backups/{name}/restore")
+	api.ProtectedRouteHandler(restorePath, r, restoreBackupHandler(backups, coinData), http.MethodPost)
+
+}
+
+// listBackupsHandler returns the coin's backups, newest first
+func listBackupsHandler(backups *Backups, coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		list, err := backups.List(coinData.CurrencyCode)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.ServerError
+			returnErr.ErrorMessage = fmt.Sprintf("List backups error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = list
+
+		apiResp.Send(w)
+
+	})
+}
+
+// createBackupHandler backs up the coin's wallet now
+func createBackupHandler(backups *Backups, coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		backup, err := backups.Create(coinData)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.ServerError
+			returnErr.ErrorMessage = fmt.Sprintf("Backup error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = backup
+
+		apiResp.Send(w)
+
+	})
+}
+
+// downloadBackupHandler sends the backup archive as an attachment
+func downloadBackupHandler(backups *Backups, coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		name := mux.Vars(r)["name"]
+
+		path, err := backups.Path(coinData.CurrencyCode, name)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.NotFound
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+
+		http.ServeFile(w, r, path)
+
+	})
+}
+
+// restoreBackupHandler restores the coin's wallet from the backup
+func restoreBackupHandler(backups *Backups, coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		name := mux.Vars(r)["name"]
+
+		if err := backups.Restore(coinData, name); err != nil {
+
+			returnErr := api.AppRespErrors.ServerError
+
+			if err == ErrBackupNotFound {
+				returnErr = api.AppRespErrors.NotFound
+			}
+
+			returnErr.ErrorMessage = fmt.Sprintf("Restore error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+
+		}
+
+		apiResp.Data = map[string]string{"restored": name}
+
+		apiResp.Send(w)
+
+	})
+}
@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stats are a cache's size and hit/miss counts since it was created
+type Stats struct {
+	Name      string `json:"name"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Persisted bool   `json:"persisted"`
+}
+
+// Cache is a bounded least recently used cache of json encoded values
+// optionally persisted to a file so it survives restarts
+type Cache struct {
+	name     string
+	capacity int
+
+	mu      sync.Mutex
+	ll      *list.List
+	items   map[string]*list.Element
+	path    string
+	dirty   bool
+	hits    uint64
+	misses  uint64
+	evicted uint64
+}
+
+// entry is a cached value, also the persisted form
+type entry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// registry is every cache created, for reporting stats
+var registry = struct {
+	sync.Mutex
+	caches []*Cache
+}{}
+
+// New creates an empty cache holding at most capacity values
+func New(name string, capacity int) *Cache {
+
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	c := &Cache{name: name, capacity: capacity, ll: list.New(), items: make(map[string]*list.Element)}
+
+	registry.Lock()
+	registry.caches = append(registry.caches, c)
+	registry.Unlock()
+
+	return c
+
+}
+
+// AllStats returns the stats of every cache, sorted by name
+func AllStats() []Stats {
+
+	registry.Lock()
+	caches := append([]*Cache{}, registry.caches...)
+	registry.Unlock()
+
+	stats := []Stats{}
+	for _, c := range caches {
+		stats = append(stats, c.Stats())
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+
+	return stats
+
+}
+
+// Name returns the cache's name
+func (c *Cache) Name() string {
+	return c.name
+}
+
+// Get decodes the value cached for key into v, reporting whether it was found
+func (c *Cache) Get(key string, v interface{}) bool {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+
+	if !ok {
+		c.misses++
+		return false
+	}
+
+	if err := json.Unmarshal(el.Value.(*entry).Value, v); err != nil {
+		c.misses++
+		c.remove(el)
+		return false
+	}
+
+	c.hits++
+	c.ll.MoveToFront(el)
+
+	return true
+
+}
+
+// Set caches the json encoding of v for key, evicting the least recently used value if full
+func (c *Cache) Set(key string, v interface{}) error {
+
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, data)
+
+	return nil
+
+}
+
+// Resize changes the number of values the cache holds, evicting the least recently used
+func (c *Cache) Resize(capacity int) {
+
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	c.evict()
+
+}
+
+// Purge empties the cache, its stats are kept
+func (c *Cache) Purge() {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.dirty = true
+
+}
+
+// Stats returns the cache's current stats
+func (c *Cache) Stats() Stats {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Name:      c.name,
+		Size:      c.ll.Len(),
+		Capacity:  c.capacity,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evicted,
+		Persisted: c.path != "",
+	}
+
+}
+
+// Persist loads the cache from the file at path, if it exists, and sets
+// the file Save writes to
+func (c *Cache) Persist(path string) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.path = path
+
+	data, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	entries := []entry{}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	// entries are saved most recently used first
+	for i := len(entries) - 1; i >= 0; i-- {
+		c.set(entries[i].Key, entries[i].Value)
+	}
+
+	c.dirty = false
+
+	return nil
+
+}
+
+// Save writes the cache to its file if it has changed since it was last saved
+func (c *Cache) Save() error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+
+	entries := make([]*entry, 0, c.ll.Len())
+	for el := c.ll.Front(); el != nil; el = el.Next() {
+		entries = append(entries, el.Value.(*entry))
+	}
+
+	data, err := json.Marshal(entries)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+
+	// write then rename so a crash never leaves a half written cache
+	tmp := c.path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+
+	return nil
+
+}
+
+// SaveEvery saves the cache at the interval until stop is closed, then saves it a last time
+func (c *Cache) SaveEvery(interval time.Duration, stop <-chan struct{}, onErr func(error)) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			if err := c.Save(); err != nil {
+				onErr(err)
+			}
+			return
+		case <-ticker.C:
+			if err := c.Save(); err != nil {
+				onErr(err)
+			}
+		}
+	}
+
+}
+
+// set stores the encoded value, the lock must be held
+func (c *Cache) set(key string, data []byte) {
+
+	c.dirty = true
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*entry).Value = data
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{Key: key, Value: data})
+
+	c.evict()
+
+}
+
+// evict drops least recently used values until the cache fits, the lock must be held
+func (c *Cache) evict() {
+	for c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+		c.evicted++
+	}
+}
+
+// remove drops the element, the lock must be held
+func (c *Cache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry).Key)
+	c.dirty = true
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test that the least recently used value is evicted and stats are counted
+func Test_Cache(t *testing.T) {
+
+	c := New("test", 2)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	var v int
+	assert.True(t, c.Get("a", &v))
+	assert.Equal(t, 1, v)
+
+	// b is now the least recently used
+	c.Set("c", 3)
+
+	assert.False(t, c.Get("b", &v))
+	assert.True(t, c.Get("c", &v))
+	assert.Equal(t, 3, v)
+
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+
+	c.Resize(1)
+	assert.False(t, c.Get("a", &v))
+	assert.Equal(t, 1, c.Stats().Size)
+
+}
+
+// test that a persisted cache is reloaded in the same order
+func Test_Persist(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data", "test-cache.json")
+
+	c := New("persisted", 2)
+	assert.Nil(t, c.Persist(path))
+
+	c.Set("a", "x")
+	c.Set("b", "y")
+	assert.Nil(t, c.Save())
+
+	loaded := New("loaded", 2)
+	assert.Nil(t, loaded.Persist(path))
+
+	var v string
+	assert.True(t, loaded.Get("b", &v))
+	assert.Equal(t, "y", v)
+
+	// a is still the least recently used after reloading
+	loaded.Set("c", "z")
+	assert.False(t, loaded.Get("a", &v))
+
+	assert.True(t, loaded.Stats().Persisted)
+
+}
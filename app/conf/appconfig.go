@@ -1,9 +1,21 @@
 package conf
 
 import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+
 	"github.com/spf13/viper"
 )
 
+// appConfigPath is the app config file that was loaded, SaveAppConfig writes it back
+var appConfigPath string
+
+// appConfigMu serialises updates to the app config
+var appConfigMu sync.Mutex
+
 // AppConfig defines a structure to store app config data
 // at present all config is mapped to Coins array
 type AppConfig struct {
@@ -11,24 +23,41 @@ type AppConfig struct {
 }
 
 // Coins defines properties of active coin
+// BootstrapURL is a chain snapshot archive extracted into an empty DataDir
+// before the daemon's first launch, it must match BootstrapSHA256
+// DaemonPin holds upgrades to a release tag, otherwise they follow DaemonChannel
+// ReleaseSource is where daemons are installed from: github (the default, using the
+// release APIs), manifest (a JSON manifest at ReleaseURL) or local (a directory at ReleaseURL)
+// DaemonPath runs a system daemon instead of downloading one, a bare name is searched for in PATH
+// DaemonOptions are extra daemon options written to the managed conf file in DataDir,
+// a list value repeats the option
 type CoinData struct {
-	Name              string `json:"name"`
-	CurrencyCode      string `json:"currencyCode"`
-	LibPath           string `json:"libPath"`
-	DataDir           string `json:"dataDir"`
-	DaemonHeartbeat   int    `json:"daemonHeartbeat"`
-	DaemonVersion     string `json:"daemonVersion"`
-	WindowsDaemonName string `json:"windowsDaemonName"`
-	DarwinDaemonName  string `json:"darwinDaemonName"`
-	LatestReleaseAPI  string `json:"latestReleaseApi"`
-	ReleaseAPI        string `json:"ReleaseApi"`
-	LivePort          int    `json:"livePort"`
-	TestNetPort       int    `json:"testnetPort"`
-	UseTestNet        bool   `json:"useTestNet"`
-	IndexTransactions bool   `json:"indexTransactions"`
-}
-
-// LoadAppConfig sets up viper, reads and parses app config
+	Name              string                 `json:"name"`
+	CurrencyCode      string                 `json:"currencyCode"`
+	LibPath           string                 `json:"libPath"`
+	DataDir           string                 `json:"dataDir"`
+	DaemonHeartbeat   int                    `json:"daemonHeartbeat"`
+	DaemonVersion     string                 `json:"daemonVersion"`
+	WindowsDaemonName string                 `json:"windowsDaemonName"`
+	DarwinDaemonName  string                 `json:"darwinDaemonName"`
+	LatestReleaseAPI  string                 `json:"latestReleaseApi"`
+	ReleaseAPI        string                 `json:"ReleaseApi"`
+	LivePort          int                    `json:"livePort"`
+	TestNetPort       int                    `json:"testnetPort"`
+	UseTestNet        bool                   `json:"useTestNet"`
+	IndexTransactions bool                   `json:"indexTransactions"`
+	BootstrapURL      string                 `json:"bootstrapUrl"`
+	BootstrapSHA256   string                 `json:"bootstrapSha256"`
+	DaemonPin         string                 `json:"daemonPin"`
+	DaemonChannel     string                 `json:"daemonChannel"`
+	ReleaseSource     string                 `json:"releaseSource"`
+	ReleaseURL        string                 `json:"releaseUrl"`
+	DaemonPath        string                 `json:"daemonPath"`
+	DaemonOptions     map[string]interface{} `json:"daemonOptions"`
+}
+
+// LoadAppConfig sets up viper, reads, parses and validates app config
+// an invalid config returns ConfigErrors listing every problem
 func LoadAppConfig() error {
 
 	viper.SetConfigName("app-config")
@@ -41,15 +70,136 @@ func LoadAppConfig() error {
 		return err
 	}
 
-	// parse out the config
-	var appConfig = AppConfig{}
-	err = viper.Unmarshal(&appConfig)
+	appConfig, err := ReadAppConfig(viper.ConfigFileUsed())
 
 	if err != nil {
 		return err
 	}
 
+	appConfigMu.Lock()
+	defer appConfigMu.Unlock()
+
 	AppConf = appConfig
 
+	appConfigPath = viper.ConfigFileUsed()
+
 	return nil
 }
+
+// ReadAppConfig parses and validates the app config file at path without loading it
+func ReadAppConfig(path string) (AppConfig, error) {
+
+	raw, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return AppConfig{}, err
+	}
+
+	// parse out the config
+	var appConfig = AppConfig{}
+
+	if err := v.Unmarshal(&appConfig); err != nil {
+		return AppConfig{}, err
+	}
+
+	if errs := ValidateAppConfig(appConfig, raw); len(errs) > 0 {
+		return AppConfig{}, errs
+	}
+
+	return appConfig, nil
+
+}
+
+// SetAppConfig replaces the loaded app config, a reload uses it once the new config is validated
+func SetAppConfig(appConfig AppConfig) {
+
+	appConfigMu.Lock()
+	defer appConfigMu.Unlock()
+
+	AppConf = appConfig
+
+}
+
+// AppConfigPath returns the app config file that was loaded
+func AppConfigPath() string {
+
+	appConfigMu.Lock()
+	defer appConfigMu.Unlock()
+
+	return appConfigPath
+
+}
+
+// ActiveCoins returns a copy of the active coins' current config, safe to
+// read while upgrades, pins and reloads change it
+func ActiveCoins() []CoinData {
+
+	appConfigMu.Lock()
+	defer appConfigMu.Unlock()
+
+	return append([]CoinData{}, AppConf.Coins...)
+
+}
+
+// ActiveCoin returns the active coin's current config
+func ActiveCoin(currencyCode string) (CoinData, bool) {
+
+	appConfigMu.Lock()
+	defer appConfigMu.Unlock()
+
+	for _, coinData := range AppConf.Coins {
+		if coinData.CurrencyCode == currencyCode {
+			return coinData, true
+		}
+	}
+
+	return CoinData{}, false
+
+}
+
+// UpdateCoin applies the update to the active coin's config and saves the app config
+func UpdateCoin(currencyCode string, update func(coinData *CoinData)) error {
+
+	appConfigMu.Lock()
+	defer appConfigMu.Unlock()
+
+	for i := range AppConf.Coins {
+		if AppConf.Coins[i].CurrencyCode == currencyCode {
+			update(&AppConf.Coins[i])
+			return SaveAppConfig()
+		}
+	}
+
+	return errors.New(currencyCode + " is not an active coin")
+
+}
+
+// SaveAppConfig writes the app config back to the file it was loaded from
+func SaveAppConfig() error {
+
+	if appConfigPath == "" {
+		return errors.New("no app config file has been loaded")
+	}
+
+	data, err := json.MarshalIndent(AppConf, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	tmp := appConfigPath + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, appConfigPath)
+
+}
@@ -0,0 +1,102 @@
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// blockedOptions are daemon options kauri-api sets itself or depends on, the
+// network is fixed by useTestNet and the wallet endpoints need the wallet,
+// a coin's DaemonOptions can't override them
+var blockedOptions = map[string]bool{
+	"addressindex":    true,
+	"conf":            true,
+	"daemon":          true,
+	"datadir":         true,
+	"devnet":          true,
+	"disablewallet":   true,
+	"regtest":         true,
+	"rpcallowip":      true,
+	"rpcauth":         true,
+	"rpcbind":         true,
+	"rpccookiefile":   true,
+	"rpcpassword":     true,
+	"rpcport":         true,
+	"rpcuser":         true,
+	"server":          true,
+	"testnet":         true,
+	"wallet":          true,
+	"zmqpubhashblock": true,
+	"zmqpubrawtx":     true,
+}
+
+// optionName is the form of a daemon option's name
+var optionName = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// DaemonOptionLines renders an option as conf file name=value lines, a list is repeated once per value
+func DaemonOptionLines(name string, value interface{}) ([]string, error) {
+
+	name = strings.TrimPrefix(name, "-")
+
+	if !optionName.MatchString(name) {
+		return nil, fmt.Errorf("%q is not a daemon option name", name)
+	}
+
+	if blockedOptions[name] {
+		return nil, fmt.Errorf("%s is set by kauri-api and can't be overridden", name)
+	}
+
+	values, ok := value.([]interface{})
+
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	lines := []string{}
+
+	for _, v := range values {
+
+		str, err := optionValue(v)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+
+		lines = append(lines, name+"="+str)
+
+	}
+
+	return lines, nil
+
+}
+
+// optionValue renders a single option value, bools as 1 or 0
+func optionValue(value interface{}) (string, error) {
+
+	switch v := value.(type) {
+
+	case string:
+		if strings.ContainsAny(v, "\r\n") {
+			return "", fmt.Errorf("value %q spans lines", v)
+		}
+		return v, nil
+
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+
+	case int, int64:
+		return fmt.Sprint(v), nil
+
+	}
+
+	return "", fmt.Errorf("unsupported value %v", value)
+
+}
@@ -1,34 +1,187 @@
 package conf
 
 import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Encrypt-S/kauri-api/app/fs"
+	"github.com/Encrypt-S/kauri-api/app/utils"
 	"github.com/spf13/viper"
 )
 
 // ServerConfig defines a structure to store server config data
+// APIToken protects the authenticated routes - one is generated
+// for the run if the config does not supply it
+// TxCache settings bound the confirmed transaction cache, defaults are used when unset
+// Backup settings schedule wallet backups every BackupInterval minutes, 0 disables
+// the schedule, and a BackupPassphrase encrypts the archives
+// GitHubToken authenticates release requests for GitHub's higher rate limit
+// DaemonLog settings rotate each daemon's output log past DaemonLogMaxSize MB, keeping DaemonLogKeep old files
 type ServerConfig struct {
-	ManagerAPIPort int64 `json:"managerApiPort"`
+	ManagerAPIPort          int64  `json:"managerApiPort"`
+	APIToken                string `json:"apiToken"`
+	TxCacheSize             int    `json:"txCacheSize"`
+	TxCacheMinConfirmations int64  `json:"txCacheMinConfirmations"`
+	TxCachePersist          bool   `json:"txCachePersist"`
+	BackupDir               string `json:"backupDir"`
+	BackupInterval          int    `json:"backupInterval"`
+	BackupKeep              int    `json:"backupKeep"`
+	BackupPassphrase        string `json:"backupPassphrase"`
+	GitHubToken             string `json:"githubToken"`
+	DaemonLogMaxSize        int    `json:"daemonLogMaxSize"`
+	DaemonLogKeep           int    `json:"daemonLogKeep"`
 }
 
-// LoadServerConfig sets up viper, reads and parses server config
+// serverConfigPath is the server config file that was loaded
+var serverConfigPath string
+
+// serverConfigMu serialises reads and updates of the server config
+var serverConfigMu sync.Mutex
+
+// LoadServerConfig sets up viper, reads, parses and validates server config
+// an invalid config returns ConfigErrors listing every problem
 func LoadServerConfig() error {
 
+	path, err := FindServerConfig()
+	if err != nil {
+		return err
+	}
+
+	serverConfig, err := ReadServerConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if serverConfig.APIToken == "" {
+		serverConfig.APIToken, err = utils.GenerateRandomString(32)
+		if err != nil {
+			return err
+		}
+		path, err := writeGeneratedToken(serverConfig.APIToken)
+		if err != nil {
+			return err
+		}
+		log.Println("No apiToken configured, generated an API token for this run in " + path)
+	}
+
+	serverConfigMu.Lock()
+	defer serverConfigMu.Unlock()
+
+	ServerConf = serverConfig
+	serverConfigPath = path
+
+	return nil
+
+}
+
+// CurrentServerConfig returns a copy of the server config, safe to read
+// while a reload changes it
+func CurrentServerConfig() ServerConfig {
+
+	serverConfigMu.Lock()
+	defer serverConfigMu.Unlock()
+
+	return ServerConf
+
+}
+
+// SetServerConfig replaces the running server config
+func SetServerConfig(serverConfig ServerConfig) {
+
+	serverConfigMu.Lock()
+	defer serverConfigMu.Unlock()
+
+	ServerConf = serverConfig
+
+}
+
+// generatedTokenFile holds a generated API token, relative to the app's path
+const generatedTokenFile = "/data/api-token"
+
+// writeGeneratedToken saves the generated API token where only its owner
+// can read it, so the secret never reaches the logs, and returns its path
+func writeGeneratedToken(token string) (string, error) {
+
+	dir, err := fs.GetCurrentPath()
+
+	if err != nil {
+		return "", err
+	}
+
+	path := dir + generatedTokenFile
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+
+	// a token left by an earlier run may have been created with other permissions
+	os.Remove(path)
+
+	if err := ioutil.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+
+	return path, nil
+
+}
+
+// FindServerConfig returns the path of the server config file viper finds
+func FindServerConfig() (string, error) {
+
 	viper.SetConfigName("server-config")
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("./")
 	viper.AddConfigPath("./app")
 	viper.AddConfigPath("../")
 
-	err := viper.ReadInConfig()
+	if err := viper.ReadInConfig(); err != nil {
+		return "", err
+	}
+
+	return viper.ConfigFileUsed(), nil
+
+}
+
+// ReadServerConfig parses and validates the server config file at path without loading it
+func ReadServerConfig(path string) (ServerConfig, error) {
+
+	raw, err := ioutil.ReadFile(path)
+
 	if err != nil {
-		return err
+		return ServerConfig{}, err
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return ServerConfig{}, err
 	}
 
 	// parse out the config
 	var serverConfig = ServerConfig{}
-	err = viper.Unmarshal(&serverConfig)
 
-	ServerConf = serverConfig
+	if err := v.Unmarshal(&serverConfig); err != nil {
+		return ServerConfig{}, err
+	}
 
-	return nil
+	if errs := ValidateServerConfig(serverConfig, raw); len(errs) > 0 {
+		return ServerConfig{}, errs
+	}
+
+	return serverConfig, nil
+
+}
+
+// ServerConfigPath returns the server config file that was loaded
+func ServerConfigPath() string {
+
+	serverConfigMu.Lock()
+	defer serverConfigMu.Unlock()
+
+	return serverConfigPath
 
 }
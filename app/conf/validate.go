@@ -0,0 +1,334 @@
+package conf
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Bounds of a coin's DaemonHeartbeat in milliseconds, 0 uses the minimum
+const (
+	minHeartbeat = 1000
+	maxHeartbeat = 60 * 60 * 1000
+)
+
+// ConfigError is a problem with one value of a config file, Path is its JSON path
+type ConfigError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ConfigError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ConfigErrors is every problem found in a config file
+type ConfigErrors []ConfigError
+
+func (errs ConfigErrors) Error() string {
+
+	lines := []string{fmt.Sprintf("%d problem(s) in the config:", len(errs))}
+
+	for _, err := range errs {
+		lines = append(lines, "  "+err.Error())
+	}
+
+	return strings.Join(lines, "\n")
+
+}
+
+// add records a problem at the path
+func (errs *ConfigErrors) add(path string, format string, args ...interface{}) {
+	*errs = append(*errs, ConfigError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateAppConfig checks the app config and each of its coins, raw is
+// the config file's JSON so misspelt fields can be reported, nil skips that
+func ValidateAppConfig(appConfig AppConfig, raw []byte) ConfigErrors {
+
+	errs := ConfigErrors{}
+
+	if raw != nil {
+		checkUnknownFields(raw, &errs)
+	}
+
+	if len(appConfig.Coins) == 0 {
+		errs.add("coins", "at least one coin must be configured")
+	}
+
+	currencies := map[string]string{}
+	dataDirs := map[string]string{}
+	ports := map[int]string{}
+
+	for i, coinData := range appConfig.Coins {
+
+		path := fmt.Sprintf("coins[%d]", i)
+
+		validateCoin(path, coinData, &errs)
+
+		if coinData.CurrencyCode != "" {
+			if other, ok := currencies[strings.ToUpper(coinData.CurrencyCode)]; ok {
+				errs.add(path+".currencyCode", "%s is also configured by %s", coinData.CurrencyCode, other)
+			} else {
+				currencies[strings.ToUpper(coinData.CurrencyCode)] = path
+			}
+		}
+
+		if coinData.DataDir != "" {
+			if other, ok := dataDirs[coinData.DataDir]; ok {
+				errs.add(path+".dataDir", "%s is also used by %s", coinData.DataDir, other)
+			} else {
+				dataDirs[coinData.DataDir] = path
+			}
+		}
+
+		// the daemons of every coin share the host so their RPC ports must differ
+		for field, port := range map[string]int{"livePort": coinData.LivePort, "testnetPort": coinData.TestNetPort} {
+			if port == 0 {
+				continue
+			}
+			if other, ok := ports[port]; ok {
+				errs.add(path+"."+field, "port %d is also used by %s", port, other)
+			} else {
+				ports[port] = path + "." + field
+			}
+		}
+
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+
+	return errs
+
+}
+
+// ValidateServerConfig checks the server config, raw is the config
+// file's JSON so misspelt fields can be reported, nil skips that
+func ValidateServerConfig(serverConfig ServerConfig, raw []byte) ConfigErrors {
+
+	errs := ConfigErrors{}
+
+	if raw != nil {
+
+		file := map[string]json.RawMessage{}
+		known := jsonFields(reflect.TypeOf(ServerConfig{}))
+
+		if err := json.Unmarshal(raw, &file); err != nil {
+			errs.add("", "is not a JSON object: %v", err)
+		}
+
+		for key := range file {
+			if !known[strings.ToLower(key)] {
+				errs.add(key, "is not a known field")
+			}
+		}
+
+	}
+
+	if serverConfig.ManagerAPIPort < 1 || serverConfig.ManagerAPIPort > 65535 {
+		errs.add("managerApiPort", "%d is not a port", serverConfig.ManagerAPIPort)
+	}
+
+	// every count and interval falls back to its default at 0
+	for field, value := range map[string]int64{
+		"txCacheSize":             int64(serverConfig.TxCacheSize),
+		"txCacheMinConfirmations": serverConfig.TxCacheMinConfirmations,
+		"backupInterval":          int64(serverConfig.BackupInterval),
+		"backupKeep":              int64(serverConfig.BackupKeep),
+		"daemonLogMaxSize":        int64(serverConfig.DaemonLogMaxSize),
+		"daemonLogKeep":           int64(serverConfig.DaemonLogKeep),
+	} {
+		if value < 0 {
+			errs.add(field, "must not be negative")
+		}
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+
+	return errs
+
+}
+
+// validateCoin checks a single coin's fields
+func validateCoin(path string, coinData CoinData, errs *ConfigErrors) {
+
+	required := map[string]string{
+		"name":          coinData.Name,
+		"currencyCode":  coinData.CurrencyCode,
+		"libPath":       coinData.LibPath,
+		"dataDir":       coinData.DataDir,
+		"daemonVersion": coinData.DaemonVersion,
+	}
+
+	for field, value := range required {
+		if strings.TrimSpace(value) == "" {
+			errs.add(path+"."+field, "is required")
+		}
+	}
+
+	if coinData.DataDir != "" && !strings.HasPrefix(coinData.DataDir, "/") {
+		errs.add(path+".dataDir", "must start with /, it is inside the kauri-api directory")
+	}
+
+	if coinData.DaemonHeartbeat != 0 && (coinData.DaemonHeartbeat < minHeartbeat || coinData.DaemonHeartbeat > maxHeartbeat) {
+		errs.add(path+".daemonHeartbeat", "must be from %d to %d milliseconds", minHeartbeat, maxHeartbeat)
+	}
+
+	for field, port := range map[string]int{"livePort": coinData.LivePort, "testnetPort": coinData.TestNetPort} {
+		if port < 0 || port > 65535 {
+			errs.add(path+"."+field, "%d is not a port", port)
+		}
+	}
+
+	// the RPC port of the network the daemon runs on is required
+	if coinData.UseTestNet && coinData.TestNetPort == 0 {
+		errs.add(path+".testnetPort", "is required when useTestNet is set")
+	} else if !coinData.UseTestNet && coinData.LivePort == 0 {
+		errs.add(path+".livePort", "is required")
+	}
+
+	switch coinData.ReleaseSource {
+
+	case "", "github":
+		// a system daemon is never downloaded
+		if coinData.DaemonPath == "" {
+			checkURL(path+".ReleaseApi", coinData.ReleaseAPI, true, errs, "http", "https")
+			checkURL(path+".latestReleaseApi", coinData.LatestReleaseAPI, true, errs, "http", "https")
+		}
+
+	case "manifest":
+		checkURL(path+".releaseUrl", coinData.ReleaseURL, true, errs, "http", "https", "file")
+
+	case "local":
+		if coinData.ReleaseURL == "" {
+			errs.add(path+".releaseUrl", "is required for a local release source")
+		} else if strings.HasPrefix(coinData.ReleaseURL, "file:") {
+			checkURL(path+".releaseUrl", coinData.ReleaseURL, true, errs, "file")
+		}
+
+	default:
+		errs.add(path+".releaseSource", "must be github, manifest or local")
+
+	}
+
+	if coinData.DaemonChannel != "" && coinData.DaemonChannel != "stable" && coinData.DaemonChannel != "prerelease" {
+		errs.add(path+".daemonChannel", "must be stable or prerelease")
+	}
+
+	checkURL(path+".bootstrapUrl", coinData.BootstrapURL, false, errs, "http", "https", "file")
+
+	if coinData.BootstrapURL != "" && coinData.BootstrapSHA256 == "" {
+		errs.add(path+".bootstrapSha256", "is required when bootstrapUrl is set")
+	}
+
+	if sum, err := hex.DecodeString(coinData.BootstrapSHA256); coinData.BootstrapSHA256 != "" && (err != nil || len(sum) != 32) {
+		errs.add(path+".bootstrapSha256", "must be a hex sha256 checksum")
+	}
+
+	for name, value := range coinData.DaemonOptions {
+		if _, err := DaemonOptionLines(name, value); err != nil {
+			errs.add(path+".daemonOptions."+name, "%v", err)
+		}
+	}
+
+}
+
+// checkURL checks the value is an absolute url with one of the schemes
+func checkURL(path string, value string, required bool, errs *ConfigErrors, schemes ...string) {
+
+	if value == "" {
+		if required {
+			errs.add(path, "is required")
+		}
+		return
+	}
+
+	parsed, err := url.Parse(value)
+
+	if err != nil {
+		errs.add(path, "is not a valid url: %v", err)
+		return
+	}
+
+	for _, scheme := range schemes {
+
+		// file://dir/a.zip reads dir as the host, the path needs a third slash
+		if parsed.Scheme == "file" && scheme == "file" {
+			if parsed.Host != "" {
+				errs.add(path, "is a file url with a host, write it as file:///path")
+			}
+			return
+		}
+
+		if parsed.Scheme == scheme && parsed.Host != "" {
+			return
+		}
+
+	}
+
+	errs.add(path, "must be a %s url", strings.Join(schemes, ", "))
+
+}
+
+// checkUnknownFields reports fields in the raw config that no config field reads,
+// matching them case insensitively as the config loader does
+func checkUnknownFields(raw []byte, errs *ConfigErrors) {
+
+	file := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(raw, &file); err != nil {
+		errs.add("", "is not a JSON object: %v", err)
+		return
+	}
+
+	for key, value := range file {
+
+		if !strings.EqualFold(key, "coins") {
+			errs.add(key, "is not a known field")
+			continue
+		}
+
+		coins := []map[string]json.RawMessage{}
+
+		if err := json.Unmarshal(value, &coins); err != nil {
+			errs.add(key, "must be a list of coins")
+			continue
+		}
+
+		known := jsonFields(reflect.TypeOf(CoinData{}))
+
+		for i, coin := range coins {
+			for field := range coin {
+				if !known[strings.ToLower(field)] {
+					errs.add(fmt.Sprintf("coins[%d].%s", i, field), "is not a known field")
+				}
+			}
+		}
+
+	}
+
+}
+
+// jsonFields returns the lowercased names and JSON names of the struct's fields
+func jsonFields(t reflect.Type) map[string]bool {
+
+	fields := map[string]bool{}
+
+	for i := 0; i < t.NumField(); i++ {
+
+		// the loader matches a field by its name as well as its tag
+		fields[strings.ToLower(t.Field(i).Name)] = true
+
+		if name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; name != "" {
+			fields[strings.ToLower(name)] = true
+		}
+
+	}
+
+	return fields
+
+}
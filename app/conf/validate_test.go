@@ -0,0 +1,196 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validCoin returns a coin that passes validation
+func validCoin() CoinData {
+	return CoinData{
+		Name:             "NavCoin",
+		CurrencyCode:     "NAV",
+		LibPath:          "navcoin",
+		DataDir:          "/lib/nav",
+		DaemonHeartbeat:  50000,
+		DaemonVersion:    "4.2.1",
+		LatestReleaseAPI: "https://api.github.com/repos/NAVCoin/navcoin-core/releases/latest",
+		ReleaseAPI:       "https://api.github.com/repos/NAVCoin/navcoin-core/releases",
+		LivePort:         44444,
+		TestNetPort:      44445,
+	}
+}
+
+// paths returns the paths of the errors
+func paths(errs ConfigErrors) []string {
+
+	result := []string{}
+
+	for _, err := range errs {
+		result = append(result, err.Path)
+	}
+
+	return result
+
+}
+
+// test that a valid config has no problems and an empty one does
+func Test_ValidateAppConfig(t *testing.T) {
+
+	assert.Empty(t, ValidateAppConfig(AppConfig{Coins: []CoinData{validCoin()}}, nil))
+
+	assert.Equal(t, []string{"coins"}, paths(ValidateAppConfig(AppConfig{}, nil)))
+
+}
+
+// test that every problem with a coin is reported at its path
+func Test_ValidateAppConfig_coin(t *testing.T) {
+
+	coin := validCoin()
+	coin.Name = ""
+	coin.CurrencyCode = ""
+	coin.DataDir = "lib/nav"
+	coin.DaemonHeartbeat = 10
+	coin.LivePort = 70000
+	coin.LatestReleaseAPI = "api.github.com/releases/latest"
+	coin.DaemonChannel = "nightly"
+	coin.BootstrapURL = "ftp://mirror/bootstrap.tar.gz"
+	coin.DaemonOptions = map[string]interface{}{"rpcuser": "admin", "dbcache": 450.0}
+
+	errs := ValidateAppConfig(AppConfig{Coins: []CoinData{coin}}, nil)
+
+	assert.Equal(t, []string{
+		"coins[0].bootstrapSha256",
+		"coins[0].bootstrapUrl",
+		"coins[0].currencyCode",
+		"coins[0].daemonChannel",
+		"coins[0].daemonHeartbeat",
+		"coins[0].daemonOptions.rpcuser",
+		"coins[0].dataDir",
+		"coins[0].latestReleaseApi",
+		"coins[0].livePort",
+		"coins[0].name",
+	}, paths(errs))
+
+	assert.Contains(t, errs.Error(), "coins[0].currencyCode: is required")
+
+	// a currency without address support runs with the endpoints that don't decode addresses
+	coin = validCoin()
+	coin.CurrencyCode = "XYZ"
+
+	assert.Empty(t, ValidateAppConfig(AppConfig{Coins: []CoinData{coin}}, nil))
+
+}
+
+// test that coins can't share a currency, data dir or port
+func Test_ValidateAppConfig_unique(t *testing.T) {
+
+	second := validCoin()
+	second.TestNetPort = 44446
+
+	errs := ValidateAppConfig(AppConfig{Coins: []CoinData{validCoin(), second}}, nil)
+
+	assert.Equal(t, []string{"coins[1].currencyCode", "coins[1].dataDir", "coins[1].livePort"}, paths(errs))
+	assert.Equal(t, "port 44444 is also used by coins[0].livePort", errs[2].Message)
+
+}
+
+// test that misspelt fields are reported, matching names as the loader does
+func Test_ValidateAppConfig_unknownFields(t *testing.T) {
+
+	raw := []byte(`{"coins": [{"currencyCode": "NAV", "WindowsDaemonName": "navcoind.exe", "ReleaseApi": "", "livePrt": 1}], "coin": []}`)
+
+	errs := ValidateAppConfig(AppConfig{Coins: []CoinData{validCoin()}}, raw)
+
+	assert.Equal(t, []string{"coin", "coins[0].livePrt"}, paths(errs))
+
+}
+
+// test that a local release source accepts a path or a file:/// url, but not a file url with a host
+func Test_ValidateAppConfig_localSource(t *testing.T) {
+
+	coin := validCoin()
+	coin.ReleaseSource = "local"
+
+	for _, releaseURL := range []string{"releases", "/opt/navcoin", "file:///opt/navcoin"} {
+		coin.ReleaseURL = releaseURL
+		assert.Empty(t, ValidateAppConfig(AppConfig{Coins: []CoinData{coin}}, nil), releaseURL)
+	}
+
+	coin.ReleaseURL = "file://releases/4.3.0"
+	errs := ValidateAppConfig(AppConfig{Coins: []CoinData{coin}}, nil)
+
+	assert.Equal(t, []string{"coins[0].releaseUrl"}, paths(errs))
+	assert.Equal(t, "is a file url with a host, write it as file:///path", errs[0].Message)
+
+}
+
+// test that the config file is read and an invalid one refused
+func Test_ReadAppConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "conf")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app-config.json")
+
+	ioutil.WriteFile(path, []byte(`{"coins": [{"name": "NavCoin", "currencyCode": "NAV", "libPath": "navcoin", "dataDir": "/lib/nav",
+		"daemonVersion": "4.2.1", "livePort": 44444, "releaseSource": "local", "releaseUrl": "/opt/navcoin",
+		"daemonOptions": {"addnode": ["10.0.0.1", "10.0.0.2"]}}]}`), 0644)
+
+	appConfig, err := ReadAppConfig(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "NAV", appConfig.Coins[0].CurrencyCode)
+	assert.Equal(t, []interface{}{"10.0.0.1", "10.0.0.2"}, appConfig.Coins[0].DaemonOptions["addnode"])
+
+	ioutil.WriteFile(path, []byte(`{"coins": [{"currencyCode": "NAV", "livePort": 44444}]}`), 0644)
+
+	_, err = ReadAppConfig(path)
+
+	assert.IsType(t, ConfigErrors{}, err)
+
+}
+
+// test that options kauri-api depends on, or that can't be rendered, are refused
+func Test_DaemonOptionLines(t *testing.T) {
+
+	lines, err := DaemonOptionLines("-maxconnections", 16.0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"maxconnections=16"}, lines)
+
+	for name, value := range map[string]interface{}{
+		"rpcpassword":   "hunter2",
+		"-datadir":      "/tmp",
+		"devnet":        1.0,
+		"disablewallet": true,
+		"zmqpubrawtx":   "tcp://0.0.0.0:1",
+		"db cache":      450.0,
+		"uacomment":     "kauri\nrpcuser=admin",
+		"addnode":       map[string]interface{}{"host": "10.0.0.1"},
+	} {
+		_, err := DaemonOptionLines(name, value)
+		assert.NotNil(t, err, "%s=%v", name, value)
+	}
+
+}
+
+// test that every problem with the server config is reported at its path
+func Test_ValidateServerConfig(t *testing.T) {
+
+	valid := ServerConfig{ManagerAPIPort: 9002, TxCacheSize: 10000, BackupInterval: 1440, BackupKeep: 10}
+
+	assert.Empty(t, ValidateServerConfig(valid, []byte(`{"managerApiPort": 9002, "BackupKeep": 10}`)))
+
+	invalid := ServerConfig{BackupInterval: -1, BackupKeep: -5, DaemonLogKeep: -1}
+
+	errs := ValidateServerConfig(invalid, []byte(`{"backupInterval": -1, "backupKep": -5}`))
+
+	assert.Equal(t, []string{"backupInterval", "backupKeep", "backupKep", "daemonLogKeep", "managerApiPort"}, paths(errs))
+
+}
@@ -0,0 +1,273 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+)
+
+// Bootstrap states reported while a snapshot is fetched and extracted
+const (
+	BootstrapDownloading = "downloading"
+	BootstrapExtracting  = "extracting"
+	BootstrapDone        = "done"
+	BootstrapSkipped     = "skipped"
+	BootstrapFailed      = "failed"
+)
+
+// ErrNoBootstrapChecksum is returned when a bootstrap url is configured without its checksum
+var ErrNoBootstrapChecksum = errors.New("bootstrapSha256 must be set to verify the bootstrap snapshot")
+
+// BootstrapStatus is the progress of a coin's bootstrap, Total is -1 when the size is unknown
+type BootstrapStatus struct {
+	Currency   string    `json:"currency"`
+	State      string    `json:"state"`
+	Downloaded int64     `json:"downloaded"`
+	Total      int64     `json:"total"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+}
+
+// bootstraps holds the status of each coin's bootstrap
+var bootstraps = map[string]*BootstrapStatus{}
+var bootstrapsMu sync.Mutex
+
+// GetBootstrapStatus returns the status of the currency's bootstrap, false if none was attempted
+func GetBootstrapStatus(currency string) (BootstrapStatus, bool) {
+
+	bootstrapsMu.Lock()
+	defer bootstrapsMu.Unlock()
+
+	status, ok := bootstraps[currency]
+
+	if !ok {
+		return BootstrapStatus{}, false
+	}
+
+	return *status, true
+
+}
+
+// updateBootstrap applies the update to the currency's status
+func updateBootstrap(currency string, update func(status *BootstrapStatus)) {
+
+	bootstrapsMu.Lock()
+	defer bootstrapsMu.Unlock()
+
+	status, ok := bootstraps[currency]
+
+	if !ok {
+		status = &BootstrapStatus{Currency: currency, StartedAt: time.Now()}
+		bootstraps[currency] = status
+	}
+
+	update(status)
+
+}
+
+// Bootstrap downloads the coin's configured chain snapshot, verifies its
+// checksum and extracts it as the data dir, only if the data dir is empty
+func Bootstrap(coinData conf.CoinData, dataDir string) error {
+
+	if coinData.BootstrapURL == "" {
+		return nil
+	}
+
+	currency := coinData.CurrencyCode
+
+	if !isEmptyDir(dataDir) {
+		log.Println(currency + " data dir is not empty, skipping bootstrap")
+		updateBootstrap(currency, func(status *BootstrapStatus) { status.State = BootstrapSkipped })
+		return nil
+	}
+
+	err := bootstrap(coinData, dataDir)
+
+	updateBootstrap(currency, func(status *BootstrapStatus) {
+		status.FinishedAt = time.Now()
+		if err != nil {
+			status.State = BootstrapFailed
+			status.Error = err.Error()
+		} else {
+			status.State = BootstrapDone
+		}
+	})
+
+	return err
+
+}
+
+// bootstrap downloads and extracts the snapshot beside the data dir, then moves it into place
+func bootstrap(coinData conf.CoinData, dataDir string) error {
+
+	currency := coinData.CurrencyCode
+
+	if coinData.BootstrapSHA256 == "" {
+		return ErrNoBootstrapChecksum
+	}
+
+	ext, err := archiveExt(coinData.BootstrapURL)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dataDir), 0755); err != nil {
+		return err
+	}
+
+	archivePath := dataDir + ".bootstrap" + ext
+	extractDir := dataDir + ".bootstrap"
+
+	defer os.Remove(archivePath)
+
+	log.Println("Bootstrapping " + currency + " from " + coinData.BootstrapURL)
+
+	checksum, err := downloadSnapshot(currency, coinData.BootstrapURL, archivePath)
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(checksum, coinData.BootstrapSHA256) {
+		return fmt.Errorf("bootstrap checksum %s does not match %s", checksum, coinData.BootstrapSHA256)
+	}
+
+	log.Println("Extracting " + currency + " bootstrap")
+
+	updateBootstrap(currency, func(status *BootstrapStatus) { status.State = BootstrapExtracting })
+
+	os.RemoveAll(extractDir)
+
+	if ext == ".zip" {
+		err = fs.Unzip(archivePath, extractDir)
+	} else {
+		err = fs.Untar(archivePath, extractDir)
+	}
+
+	if err != nil {
+		os.RemoveAll(extractDir)
+		return err
+	}
+
+	// the data dir is empty, swap it for the extracted snapshot
+	if err := os.RemoveAll(dataDir); err != nil {
+		return err
+	}
+
+	if err := os.Rename(extractDir, dataDir); err != nil {
+		return err
+	}
+
+	log.Println(currency + " bootstrap complete")
+
+	return nil
+
+}
+
+// downloadSnapshot writes the snapshot to path, reporting progress, and returns its sha256
+func downloadSnapshot(currency string, snapshotURL string, path string) (string, error) {
+
+	body, total, err := fs.Fetch(snapshotURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer body.Close()
+
+	updateBootstrap(currency, func(status *BootstrapStatus) {
+		status.State = BootstrapDownloading
+		status.Total = total
+	})
+
+	out, err := os.Create(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer out.Close()
+
+	hash := sha256.New()
+	progress := &progressWriter{currency: currency, total: total}
+
+	if _, err := io.Copy(io.MultiWriter(out, hash, progress), body); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), out.Close()
+
+}
+
+// progressWriter counts the bytes downloaded into the coin's status,
+// logging every tenth of a snapshot of known size
+type progressWriter struct {
+	currency string
+	total    int64
+	written  int64
+	logged   int64
+}
+
+// Write records the downloaded bytes
+func (p *progressWriter) Write(b []byte) (int, error) {
+
+	p.written += int64(len(b))
+
+	updateBootstrap(p.currency, func(status *BootstrapStatus) { status.Downloaded = p.written })
+
+	if p.total > 0 && p.written*10/p.total > p.logged {
+		p.logged = p.written * 10 / p.total
+		log.Printf("%s bootstrap %d%% downloaded", p.currency, p.logged*10)
+	}
+
+	return len(b), nil
+
+}
+
+// archiveExt returns the extension of the snapshot url's archive type
+func archiveExt(snapshotURL string) (string, error) {
+
+	parsed, err := url.Parse(snapshotURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.HasSuffix(parsed.Path, ".zip"):
+		return ".zip", nil
+	case strings.HasSuffix(parsed.Path, ".tar.gz"), strings.HasSuffix(parsed.Path, ".tgz"):
+		return ".tar.gz", nil
+	}
+
+	return "", fmt.Errorf("bootstrap snapshot must be a .zip or .tar.gz archive")
+
+}
+
+// isEmptyDir reports whether the dir is missing or has no entries
+func isEmptyDir(dir string) bool {
+
+	entries, err := ioutil.ReadDir(dir)
+
+	if os.IsNotExist(err) {
+		return true
+	}
+
+	return err == nil && len(entries) == 0
+
+}
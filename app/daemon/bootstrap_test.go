@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockSnapshot builds a tar.gz chain snapshot and returns it with its sha256
+func mockSnapshot(t *testing.T) ([]byte, string) {
+
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+
+	// the blocks dir has no entry of its own
+	for name, contents := range map[string]string{"blocks/blk00000.dat": "blocks", "chainstate/CURRENT": "chainstate"} {
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		tw.Write([]byte(contents))
+	}
+
+	tw.Close()
+	gzw.Close()
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	return buf.Bytes(), hex.EncodeToString(sum[:])
+
+}
+
+// test that a snapshot from a file:// url is extracted as the data dir
+func Test_Bootstrap_file(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "bootstrap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	snapshot, checksum := mockSnapshot(t)
+	snapshotPath := filepath.Join(dir, "snapshot.tar.gz")
+	ioutil.WriteFile(snapshotPath, snapshot, 0644)
+
+	coinData := conf.CoinData{CurrencyCode: "NAVFILE", BootstrapURL: "file://" + snapshotPath, BootstrapSHA256: checksum}
+	dataDir := filepath.Join(dir, "data", "nav")
+
+	err = Bootstrap(coinData, dataDir)
+
+	assert.Nil(t, err)
+
+	blocks, err := ioutil.ReadFile(filepath.Join(dataDir, "blocks", "blk00000.dat"))
+	assert.Nil(t, err)
+	assert.Equal(t, "blocks", string(blocks))
+
+	status, ok := GetBootstrapStatus("NAVFILE")
+
+	assert.True(t, ok)
+	assert.Equal(t, BootstrapDone, status.State)
+	assert.Equal(t, int64(len(snapshot)), status.Downloaded)
+	assert.Equal(t, int64(len(snapshot)), status.Total)
+
+	// the archive isn't left beside the data dir
+	assert.False(t, fs.Exists(dataDir+".bootstrap.tar.gz"))
+
+	// a data dir with contents isn't bootstrapped again
+	err = Bootstrap(coinData, dataDir)
+
+	assert.Nil(t, err)
+
+	status, _ = GetBootstrapStatus("NAVFILE")
+	assert.Equal(t, BootstrapSkipped, status.State)
+
+}
+
+// test that a snapshot from a loopback mirror must match its checksum
+func Test_Bootstrap_checksum(t *testing.T) {
+
+	snapshot, checksum := mockSnapshot(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(snapshot)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "bootstrap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dataDir := filepath.Join(dir, "nav")
+
+	coinData := conf.CoinData{CurrencyCode: "NAVHTTP", BootstrapURL: server.URL + "/snapshot.tar.gz", BootstrapSHA256: "00" + checksum[2:]}
+
+	err = Bootstrap(coinData, dataDir)
+
+	assert.NotNil(t, err)
+	assert.True(t, isEmptyDir(dataDir))
+
+	status, _ := GetBootstrapStatus("NAVHTTP")
+	assert.Equal(t, BootstrapFailed, status.State)
+
+	coinData.BootstrapSHA256 = checksum
+
+	err = Bootstrap(coinData, dataDir)
+
+	assert.Nil(t, err)
+	assert.False(t, isEmptyDir(dataDir))
+
+	// a snapshot can't be used unverified
+	coinData = conf.CoinData{CurrencyCode: "NAVHTTP", BootstrapURL: server.URL + "/snapshot.tar.gz"}
+
+	err = Bootstrap(coinData, filepath.Join(dir, "unverified"))
+	assert.Equal(t, ErrNoBootstrapChecksum, err)
+
+}
@@ -1,11 +1,9 @@
 package daemon
 
 import (
-	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"log"
-	"net/http"
+	"net"
 	"os/exec"
 	"path/filepath"
 	"runtime"
@@ -15,6 +13,8 @@ import (
 	"fmt"
 
 	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonevents"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonzmq"
 	"github.com/Encrypt-S/kauri-api/app/fs"
 )
 
@@ -99,13 +99,12 @@ type GitHubReleaseData struct {
 	Body       string `json:"body"`
 }
 
-var runningDaemon *exec.Cmd
 var minHeartbeat = 1000 // the lowest value the hb checker can be set to
 
 var isGettingDaemon = false
 
 // StartManager is a simple system that checks if the coin's daemon
-// is alive. If not it tries to startCoinDaemons it with proper config
+// is alive. If not it tries to launch it with proper config
 // It is called from the StartAllDaemonManagers function in managers pkg
 func StartManager(coinData conf.CoinData) {
 
@@ -120,11 +119,9 @@ func StartManager(coinData conf.CoinData) {
 
 	// kick off goroutine for DownloadAndStart
 	go func() {
-		cmd, err := DownloadAndStart(coinData)
+		_, err := DownloadAndStart(coinData)
 		if err != nil {
 			log.Println(err)
-		} else {
-			runningDaemon = cmd
 		}
 
 	}()
@@ -155,15 +152,50 @@ func DownloadAndStart(coinData conf.CoinData) (*exec.Cmd, error) {
 
 	path, err := CheckForDaemon(coinData)
 
-	// download daemon if not found
+	// download daemon if not found, a system daemon is never downloaded
 	if err != nil {
-		downloadDaemons(coinData)
-	} else {
-		return startCoinDaemons(coinData, path), nil
+
+		if coinData.DaemonPath != "" {
+			return nil, err
+		}
+
+		if err := downloadDaemons(coinData); err != nil {
+			return nil, errors.New("Failed to download the " + coinData.CurrencyCode + " daemon: " + err.Error())
+		}
+
+		if path, err = CheckForDaemon(coinData); err != nil {
+			return nil, err
+		}
+
+	}
+
+	// fill an empty data dir from the coin's chain snapshot before the first launch
+	bootstrapDataDir(coinData)
+
+	cmd, err := launchDaemon(coinData, path)
+
+	if err != nil {
+		return nil, errors.New("Failed to start the " + coinData.CurrencyCode + " daemon: " + err.Error())
 	}
 
-	// if found, just start it up
-	return startCoinDaemons(coinData, path), nil
+	return cmd, nil
+
+}
+
+// bootstrapDataDir bootstraps the coin's data dir at the app's path,
+// a failed bootstrap leaves the daemon to sync from scratch
+func bootstrapDataDir(coinData conf.CoinData) {
+
+	path, err := fs.GetCurrentPath()
+
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if err := Bootstrap(coinData, path+coinData.DataDir); err != nil {
+		log.Println("Failed to bootstrap the " + coinData.CurrencyCode + " daemon, it will sync from scratch: " + err.Error())
+	}
 
 }
 
@@ -176,20 +208,22 @@ func DownloadAndStart(coinData conf.CoinData) (*exec.Cmd, error) {
 // }
 
 // CheckForDaemon checks for current coin's daemon
-// in appropriate path and reports back to DownLoadAndStartDaemons
+// in appropriate path and reports back to DownLoadAndStartDaemons,
+// a coin with a DaemonPath uses the system's daemon instead
 func CheckForDaemon(coinData conf.CoinData) (string, error) {
 
+	if coinData.DaemonPath != "" {
+		return checkSystemDaemon(coinData)
+	}
+
 	// get the latest release version, equal to daemon version
 	releaseVersion := coinData.DaemonVersion
 
-	// get the apps current path
-	path, err := fs.GetCurrentPath()
+	path, err := daemonPath(coinData)
 	if err != nil {
 		return "", err
 	}
 
-	// build the path for current daemon
-	path += "/lib/" + coinData.LibPath + "-" + releaseVersion + "/bin/" + getOSInfo(coinData).DaemonName
 	log.Println("Searching for " + coinData.CurrencyCode + " daemon at " + path)
 
 	// check that the current daemon exists
@@ -203,9 +237,22 @@ func CheckForDaemon(coinData conf.CoinData) (string, error) {
 
 }
 
-// startCoinDaemons pulls in config for coin data, daemonPath,
-// builds the command arguments, and executes start command
-func startCoinDaemons(coinData conf.CoinData, daemonPath string) *exec.Cmd {
+// daemonPath builds the path a daemon of the coin's DaemonVersion is installed at
+func daemonPath(coinData conf.CoinData) (string, error) {
+
+	// get the apps current path
+	path, err := fs.GetCurrentPath()
+	if err != nil {
+		return "", err
+	}
+
+	return path + "/lib/" + coinData.LibPath + "-" + coinData.DaemonVersion + "/bin/" + getOSInfo(coinData).DaemonName, nil
+
+}
+
+// launchDaemon starts the daemon at daemonPath with the coin's flags
+// and registers it with the manager
+func launchDaemon(coinData conf.CoinData, daemonPath string) (*exec.Cmd, error) {
 
 	log.Println("Booting " + coinData.CurrencyCode + " daemon")
 
@@ -230,16 +277,84 @@ func startCoinDaemons(coinData conf.CoinData, daemonPath string) *exec.Cmd {
 	s := fmt.Sprintf("-datadir=%s", p)
 	cmdStr = append(cmdStr, s)
 
+	// the coin's own daemon options are rendered into a managed conf file
+	confPath, err := writeDaemonConf(coinData, p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cmdStr = append(cmdStr, "-conf="+confPath)
+
+	// publish block and tx notifications on a loopback port we choose
+	zmqEndpoint, err := chooseZMQEndpoint()
+
+	if err != nil {
+		log.Println("Failed to choose a ZMQ port for the " + coinData.CurrencyCode + " daemon, notifications disabled: " + err.Error())
+	} else {
+		cmdStr = append(cmdStr, "-zmqpubhashblock="+zmqEndpoint, "-zmqpubrawtx="+zmqEndpoint)
+	}
+
 	// setup to index transactions (required for API functionality)
 	cmd := exec.Command(daemonPath, cmdStr...)
 
-	err := cmd.Start()
+	// capture the daemon's output so failed starts can be diagnosed
+	if output, err := daemonOutput(coinData); err != nil {
+		log.Println("Failed to open the " + coinData.CurrencyCode + " daemon output log, its output is discarded: " + err.Error())
+	} else {
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
+
+	err = cmd.Start()
+
+	if err != nil {
+		return nil, err
+	}
+
+	proc := register(coinData, cmd, daemonPath)
+
+	if zmqEndpoint != "" {
+		subscribeNotifications(coinData, proc, zmqEndpoint)
+	}
+
+	return cmd, nil
+
+}
+
+// chooseZMQEndpoint finds a free loopback port for the daemon's ZMQ notifications
+func chooseZMQEndpoint() (string, error) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
 
 	if err != nil {
-		log.Fatal("Failed to startCoinDaemons the " + coinData.CurrencyCode + "daemon: " + err.Error())
+		return "", err
 	}
 
-	return cmd
+	defer l.Close()
+
+	return "tcp://" + l.Addr().String(), nil
+
+}
+
+// subscribeNotifications feeds the daemon's ZMQ notifications into the
+// server's event stream until the daemon process exits
+func subscribeNotifications(coinData conf.CoinData, proc *process, endpoint string) {
+
+	stop := make(chan struct{})
+
+	sub := &daemonzmq.Subscriber{
+		Currency: coinData.CurrencyCode,
+		Endpoint: endpoint,
+		Stream:   daemonevents.Events,
+	}
+
+	go sub.Run(stop)
+
+	go func() {
+		<-proc.exited
+		close(stop)
+	}()
 
 }
 
@@ -279,40 +394,43 @@ func getOSInfo(coinData conf.CoinData) OSInfo {
 
 // downloadDaemons pieces together release info, path, name
 // and passes that info to DownloadExtract function
-func downloadDaemons(coinData conf.CoinData) {
+func downloadDaemons(coinData conf.CoinData) error {
+
+	releaseInfo, err := getReleaseDataForVersion(coinData)
 
-	releaseInfo, _ := getReleaseDataForVersion(coinData)
+	if err != nil {
+		return err
+	}
 
 	dlPath, dlName, _ := getDownloadPathAndName(coinData, releaseInfo)
 
-	log.Println("Attempting to get release data for " + coinData.CurrencyCode + " daemon v" + coinData.DaemonVersion)
+	if dlPath == "" {
+		return fmt.Errorf("%s daemon v%s has no release for this platform", coinData.CurrencyCode, coinData.DaemonVersion)
+	}
 
 	isGettingDaemon = true // flag we are getting the daemon
 
-	fs.DownloadExtract(dlPath, dlName)
+	err = fs.DownloadExtract(dlPath, dlName)
 
 	isGettingDaemon = false // flag we have finished
 
+	return err
+
 }
 
-// getReleaseDataForVersion ranges through the releases and matches
-// release TagName to the coin's DaemonVersion via gitHubReleaseInfo function
-func getReleaseDataForVersion(coinData conf.CoinData) (GitHubReleaseData, error) {
+// getReleaseDataForVersion finds the release matching the
+// coin's DaemonVersion in its release source
+func getReleaseDataForVersion(coinData conf.CoinData) (SourceRelease, error) {
 
 	log.Println("Attempting to get release data for " + coinData.CurrencyCode + " daemon v" + coinData.DaemonVersion)
 
-	releases, err := gitHubReleaseInfo(coinData.CurrencyCode, coinData.ReleaseAPI)
+	releases, err := sourceReleases(coinData)
 
-	var e = GitHubReleaseData{}
-
-	for _, elem := range releases {
-		if elem.TagName == coinData.DaemonVersion {
-			log.Println("Release data found for " + coinData.CurrencyCode + " daemon v" + coinData.DaemonVersion)
-			e = elem.GitHubReleaseData
-		}
+	if err != nil {
+		return SourceRelease{}, err
 	}
 
-	return e, err
+	return releaseForTag(releases, coinData.DaemonVersion)
 
 }
 
@@ -321,33 +439,23 @@ func gitHubReleaseInfo(currencyCode string, releaseAPI string) (GitHubReleases,
 
 	log.Println("Retrieving " + currencyCode + " Github release data from: " + releaseAPI)
 
-	response, err := http.Get(releaseAPI)
+	releases := GitHubReleases{}
 
-	if err != nil {
-		log.Printf("The HTTP request failed with error %s\n", err)
+	if err := gitHubGet(releaseAPI, &releases); err != nil {
+		log.Println("Failed to retrieve " + currencyCode + " Github release data: " + err.Error())
 		return GitHubReleases{}, err
 	}
 
-	// read the data out to json
-	data, _ := ioutil.ReadAll(response.Body)
-	c := GitHubReleases{}
-	jsonErr := json.Unmarshal(data, &c)
+	return releases, nil
 
-	if jsonErr != nil {
-		return GitHubReleases{}, jsonErr
-	}
-
-	return c, nil
 }
 
 // getDownloadPathAndName ranges through release assets
 // and builds/returns downloadPath and downloadName
-func getDownloadPathAndName(coinData conf.CoinData, gitHubReleaseData GitHubReleaseData) (string, string, error) {
+func getDownloadPathAndName(coinData conf.CoinData, releaseInfo SourceRelease) (string, string, error) {
 
 	log.Println("Getting download path/name for OS from " + coinData.CurrencyCode + " release assest data")
 
-	releaseInfo := gitHubReleaseData
-
 	downloadPath := ""
 	downloadName := ""
 
@@ -360,14 +468,14 @@ func getDownloadPathAndName(coinData conf.CoinData, gitHubReleaseData GitHubRele
 			if strings.Contains(asset.Name, "win") {
 				if filepath.Ext(asset.Name) == ".zip" {
 					log.Println("win64 detected - preparing " + coinData.CurrencyCode + " daemon .zip download")
-					downloadPath = releaseInfo.Assets[e].BrowserDownloadURL
+					downloadPath = releaseInfo.Assets[e].URL
 					downloadName = releaseInfo.Assets[e].Name
 				}
 			}
 			// osx64 check to provide gzip package :: tar.gz
 			if strings.Contains(asset.Name, "osx64") {
 				log.Println("osx64 detected - preparing " + coinData.CurrencyCode + " daemon tar.gz download")
-				downloadPath = releaseInfo.Assets[e].BrowserDownloadURL
+				downloadPath = releaseInfo.Assets[e].URL
 				downloadName = releaseInfo.Assets[e].Name
 			}
 
@@ -0,0 +1,44 @@
+package daemonapi
+
+import (
+	"net/http"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon"
+	"github.com/gorilla/mux"
+)
+
+// InitBootstrapHandlers sets up the handler reporting the coin's chain snapshot bootstrap
+func InitBootstrapHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "bootstrap"
+
+	// status endpoint :: provides the bootstrap's state and download progress
+	statusPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "status")
+	api.OpenRouteHandler(statusPath, r, bootstrapStatusHandler(coinData))
+
+}
+
+// bootstrapStatusHandler returns the coin's bootstrap status
+func bootstrapStatusHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		status, ok := daemon.GetBootstrapStatus(coinData.CurrencyCode)
+
+		if !ok {
+			returnErr := api.AppRespErrors.NotFound
+			returnErr.ErrorMessage = "No bootstrap has run for " + coinData.CurrencyCode
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = status
+
+		apiResp.Send(w)
+
+	})
+}
@@ -0,0 +1,196 @@
+package daemonapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Encrypt-S/kauri-api/app/address"
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/gorilla/mux"
+)
+
+// InitColdStakingHandlers sets up handlers for cold staking addresses and delegated balances
+func InitColdStakingHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "coldstaking"
+
+	// address endpoint :: builds the cold staking address for a ?staking= and ?spending= address
+	addressPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "address")
+	api.OpenRouteHandler(addressPath, r, coldStakingAddressHandler(coinData))
+
+	// balance endpoint :: splits an ?address= balance into spendable and cold staking coins
+	balancePath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "balance")
+	api.OpenRouteHandler(balancePath, r, coldStakingBalanceHandler(coinData))
+
+}
+
+// ColdStakingAddress is a cold staking address and the addresses it combines
+type ColdStakingAddress struct {
+	Address         string `json:"address"`
+	StakingAddress  string `json:"stakingaddress"`
+	SpendingAddress string `json:"spendingaddress"`
+}
+
+// ColdStakingBalance is an address's balance in satoshis split by how it is held
+// Spendable is held by the address, Delegated is its own coins in cold staking
+// outputs staked by another address and StakingFor is other addresses' coins
+// in cold staking outputs the address stakes
+type ColdStakingBalance struct {
+	Address    string `json:"address"`
+	Spendable  int64  `json:"spendable"`
+	Delegated  int64  `json:"delegated"`
+	StakingFor int64  `json:"stakingfor"`
+}
+
+// AddressUtxo is an entry of the 'getaddressutxos' RPC result
+type AddressUtxo struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	OutputIndex int    `json:"outputIndex"`
+	Script      string `json:"script"`
+	Satoshis    int64  `json:"satoshis"`
+	Height      int64  `json:"height"`
+}
+
+// coldStakingAddressHandler returns the cold staking address for the ?staking= and ?spending= addresses
+func coldStakingAddressHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		staking := r.URL.Query().Get("staking")
+		spending := r.URL.Query().Get("spending")
+
+		for _, addressStr := range []string{staking, spending} {
+			if err := validateAddress(coinData, addressStr); err != nil {
+				returnErr := api.AppRespErrors.InvalidAddress
+				returnErr.ErrorMessage = err.Error()
+				apiResp.Errors = append(apiResp.Errors, returnErr)
+				apiResp.Send(w)
+				return
+			}
+		}
+
+		coldStakingAddress, err := getColdStakingAddress(coinData, staking, spending)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Cold staking address error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = ColdStakingAddress{Address: coldStakingAddress, StakingAddress: staking, SpendingAddress: spending}
+
+		apiResp.Send(w)
+
+	})
+}
+
+// coldStakingBalanceHandler returns the balance of the ?address= split by how it is held
+func coldStakingBalanceHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		addressStr := r.URL.Query().Get("address")
+
+		if err := validateAddress(coinData, addressStr); err != nil {
+			returnErr := api.AppRespErrors.InvalidAddress
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		balance, err := getColdStakingBalance(coinData, addressStr)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Cold staking balance error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = balance
+
+		apiResp.Send(w)
+
+	})
+}
+
+// validateAddress checks the address is valid for the coin, if its currency is supported
+func validateAddress(coinData conf.CoinData, addressStr string) error {
+
+	if !address.IsSupported(coinData.CurrencyCode) {
+		return nil
+	}
+
+	return address.Validate(coinData.CurrencyCode, addressStr, coinData.UseTestNet)
+
+}
+
+// getColdStakingAddress asks the daemon for the cold staking address of the
+// staking and spending addresses, checking it against the locally built one
+func getColdStakingAddress(coinData conf.CoinData, staking string, spending string) (string, error) {
+
+	coldStakingAddress := ""
+
+	if err := daemonrpc.CallDaemon(coinData, "getcoldstakingaddress", []interface{}{staking, spending}, &coldStakingAddress); err != nil {
+		return "", err
+	}
+
+	if !address.IsSupported(coinData.CurrencyCode) {
+		return coldStakingAddress, nil
+	}
+
+	expected, err := address.ColdStakingAddress(coinData.CurrencyCode, staking, spending, coinData.UseTestNet)
+
+	if err != nil {
+		return "", err
+	}
+
+	if expected != coldStakingAddress {
+		return "", fmt.Errorf("daemon returned %s, expected %s", coldStakingAddress, expected)
+	}
+
+	return coldStakingAddress, nil
+
+}
+
+// getColdStakingBalance sums the address's unspent outputs, telling
+// its cold staking outputs apart by their script
+func getColdStakingBalance(coinData conf.CoinData, addressStr string) (ColdStakingBalance, error) {
+
+	utxos := []AddressUtxo{}
+	params := GetTxIDParams{Addresses: []string{addressStr}}
+
+	if err := daemonrpc.CallDaemon(coinData, "getaddressutxos", []interface{}{params}, &utxos); err != nil {
+		return ColdStakingBalance{}, err
+	}
+
+	balance := ColdStakingBalance{Address: addressStr}
+
+	for _, utxo := range utxos {
+
+		vout := VerboseVout{ValueSat: utxo.Satoshis, ScriptPubKey: ScriptPubKey{Hex: utxo.Script, Addresses: []string{utxo.Address}}}
+		output := vout.Output(coinData)
+
+		switch {
+		case output.Type != OutputTypeColdStaking:
+			balance.Spendable += output.Value
+		case output.StakingAddress == addressStr:
+			balance.StakingFor += output.Value
+		default:
+			balance.Delegated += output.Value
+		}
+
+	}
+
+	return balance, nil
+
+}
@@ -0,0 +1,109 @@
+package daemonapi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/Encrypt-S/kauri-api/app/address"
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+var (
+	testStakingHash  = bytes.Repeat([]byte{0x11}, 20)
+	testSpendingHash = bytes.Repeat([]byte{0x22}, 20)
+)
+
+// coldStakingScript builds a cold staking output script for the test key hashes
+func coldStakingScript() string {
+	return "c663" + "76a914" + hex.EncodeToString(testStakingHash) + "88ac" +
+		"67" + "76a914" + hex.EncodeToString(testSpendingHash) + "88ac" + "68"
+}
+
+// test that cold staking outputs are recognised and owned by the spending address
+func Test_Output_coldStaking(t *testing.T) {
+
+	stakingAddr, _ := address.EncodePubKeyHash("NAV", testStakingHash, false)
+	spendingAddr, _ := address.EncodePubKeyHash("NAV", testSpendingHash, false)
+	coldStakingAddr, _ := address.EncodeColdStaking("NAV", testStakingHash, testSpendingHash, false)
+
+	vout := VerboseVout{N: 1, ValueSat: 500, ScriptPubKey: ScriptPubKey{Hex: coldStakingScript(), Type: "nonstandard"}}
+	output := vout.Output(rpctest.CoinData())
+
+	assert.Equal(t, OutputTypeColdStaking, output.Type)
+	assert.Equal(t, coldStakingAddr, output.Address)
+	assert.Equal(t, stakingAddr, output.StakingAddress)
+	assert.Equal(t, spendingAddr, output.SpendingAddress)
+
+	verboseTx := VerboseTx{TxID: "abc", Confirmations: 1, Vout: []VerboseVout{vout}}
+	inputs := []TxInput{{TxID: "prev", Address: spendingAddr, Value: 600}}
+
+	delegated := buildTransaction(rpctest.CoinData(), verboseTx, inputs, spendingAddr)
+
+	assert.Equal(t, int64(-100), delegated.Amount)
+
+	staked := buildTransaction(rpctest.CoinData(), verboseTx, inputs, stakingAddr)
+
+	assert.Equal(t, int64(0), staked.Amount)
+
+}
+
+// test that the daemon's cold staking address is checked against the local one
+func Test_getColdStakingAddress(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	stakingAddr, _ := address.EncodePubKeyHash("NAV", testStakingHash, false)
+	spendingAddr, _ := address.EncodePubKeyHash("NAV", testSpendingHash, false)
+	coldStakingAddr, _ := address.EncodeColdStaking("NAV", testStakingHash, testSpendingHash, false)
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{"getcoldstakingaddress": fmt.Sprintf(`{"result": "%s", "error": null}`, coldStakingAddr)}))
+
+	result, err := getColdStakingAddress(rpctest.CoinData(), stakingAddr, spendingAddr)
+
+	assert.Nil(t, err)
+	assert.Equal(t, coldStakingAddr, result)
+
+	// the addresses the other way round don't match what the daemon returned
+	_, err = getColdStakingAddress(rpctest.CoinData(), spendingAddr, stakingAddr)
+
+	assert.NotNil(t, err)
+
+}
+
+// test that delegated and staked coins are reported apart from spendable coins
+func Test_getColdStakingBalance(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	spendingAddr, _ := address.EncodePubKeyHash("NAV", testSpendingHash, false)
+	stakingAddr, _ := address.EncodePubKeyHash("NAV", testStakingHash, false)
+
+	utxos := fmt.Sprintf(`{"result": [
+  {"address": "%[1]s", "txid": "a1", "outputIndex": 0, "script": "76a914%[2]s88ac", "satoshis": 100, "height": 10},
+  {"address": "%[1]s", "txid": "a2", "outputIndex": 1, "script": "%[3]s", "satoshis": 250, "height": 11}
+], "error": null}`, spendingAddr, hex.EncodeToString(testSpendingHash), coldStakingScript())
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{"getaddressutxos": utxos}))
+
+	balance, err := getColdStakingBalance(rpctest.CoinData(), spendingAddr)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), balance.Spendable)
+	assert.Equal(t, int64(250), balance.Delegated)
+	assert.Equal(t, int64(0), balance.StakingFor)
+
+	balance, err = getColdStakingBalance(rpctest.CoinData(), stakingAddr)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(250), balance.StakingFor)
+	assert.Equal(t, int64(0), balance.Delegated)
+
+}
@@ -0,0 +1,360 @@
+package daemonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/cache"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/gorilla/mux"
+)
+
+// Proposal states that can be filtered on, as accepted by 'listproposals'
+var proposalStates = map[string]bool{
+	"pending":  true,
+	"accepted": true,
+	"rejected": true,
+	"expired":  true,
+}
+
+// daoCacheSize is the number of dao responses cached, keyed by best block
+const daoCacheSize = 500
+
+// daoCache holds dao responses keyed by currency and best block hash
+// so they are only requested from the daemon once per block
+var daoCache = cache.New("dao", daoCacheSize)
+
+// InitDAOHandlers sets up handlers for the Community Fund proposals and payment requests
+func InitDAOHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "dao"
+
+	// proposals endpoint :: lists proposals, optionally of a single ?state=
+	proposalsPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "proposals")
+	api.OpenRouteHandler(proposalsPath, r, proposalsHandler(coinData))
+
+	// proposal endpoint :: provides a single proposal by hash
+	proposalPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "proposals/{hash}")
+	api.OpenRouteHandler(proposalPath, r, proposalHandler(coinData))
+
+	// payment request endpoint :: provides a single payment request by hash
+	paymentRequestPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "paymentrequests/{hash}")
+	api.OpenRouteHandler(paymentRequestPath, r, paymentRequestHandler(coinData))
+
+	// stats endpoint :: provides the fund's balances and voting period
+	statsPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "stats")
+	api.OpenRouteHandler(statsPath, r, daoStatsHandler(coinData))
+
+	// votes endpoint :: lists the proposals with the node's votes on them
+	votesPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "votes")
+	api.ProtectedRouteHandler(votesPath, r, nodeVotesHandler(coinData), http.MethodGet)
+
+	// proposal vote endpoint :: sets the node's vote on a proposal
+	proposalVotePath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "proposals/{hash}/vote")
+	api.ProtectedRouteHandler(proposalVotePath, r, voteHandler(coinData, "proposalvote"), http.MethodPost)
+
+	// payment request vote endpoint :: sets the node's vote on a payment request
+	paymentRequestVotePath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "paymentrequests/{hash}/vote")
+	api.ProtectedRouteHandler(paymentRequestVotePath, r, voteHandler(coinData, "paymentrequestvote"), http.MethodPost)
+
+}
+
+// Amount is a satoshi amount decoded from a daemon coin value
+// the Community Fund RPC calls return coin values as numbers or strings
+type Amount int64
+
+// UnmarshalJSON decodes a coin value number or string into satoshis
+func (amount *Amount) UnmarshalJSON(b []byte) error {
+
+	var value float64
+
+	if err := json.Unmarshal(b, &value); err != nil {
+
+		var str string
+
+		if err := json.Unmarshal(b, &str); err != nil {
+			return err
+		}
+
+		if value, err = strconv.ParseFloat(str, 64); err != nil {
+			return err
+		}
+
+	}
+
+	*amount = Amount(value*satoshisPerCoin + 0.5)
+
+	return nil
+
+}
+
+// Proposal is a Community Fund proposal, amounts are in satoshis and
+// Deadline is the unix time the proposal expires if it was included in a block
+// Vote is only set when listing the node's votes
+type Proposal struct {
+	Version             int              `json:"version"`
+	Hash                string           `json:"hash"`
+	BlockHash           string           `json:"blockHash"`
+	Description         string           `json:"description"`
+	RequestedAmount     Amount           `json:"requestedAmount"`
+	NotPaidYet          Amount           `json:"notPaidYet"`
+	UserPaidFee         Amount           `json:"userPaidFee"`
+	PaymentAddress      string           `json:"paymentAddress"`
+	ProposalDuration    int64            `json:"proposalDuration"`
+	Deadline            int64            `json:"deadline,omitempty"`
+	VotesYes            int64            `json:"votesYes"`
+	VotesNo             int64            `json:"votesNo"`
+	VotingCycle         int64            `json:"votingCycle"`
+	Status              string           `json:"status"`
+	State               int              `json:"state"`
+	StateChangedOnBlock string           `json:"stateChangedOnBlock,omitempty"`
+	Vote                string           `json:"vote,omitempty"`
+	PaymentRequests     []PaymentRequest `json:"paymentRequests"`
+}
+
+// PaymentRequest is a request for payment against an accepted proposal
+type PaymentRequest struct {
+	Version             int    `json:"version"`
+	Hash                string `json:"hash"`
+	BlockHash           string `json:"blockHash"`
+	ProposalHash        string `json:"proposalHash"`
+	Description         string `json:"description"`
+	RequestedAmount     Amount `json:"requestedAmount"`
+	VotesYes            int64  `json:"votesYes"`
+	VotesNo             int64  `json:"votesNo"`
+	VotingCycle         int64  `json:"votingCycle"`
+	Status              string `json:"status"`
+	State               int    `json:"state"`
+	StateChangedOnBlock string `json:"stateChangedOnBlock,omitempty"`
+	PaidOnBlock         string `json:"paidOnBlock,omitempty"`
+	Vote                string `json:"vote,omitempty"`
+}
+
+// DAOStats is the decoded result of a 'cfundstats' RPC call
+// the consensus parameters and votes are passed through as the daemon reports them
+type DAOStats struct {
+	Funds struct {
+		Available Amount `json:"available"`
+		Locked    Amount `json:"locked"`
+	} `json:"funds"`
+	VotingPeriod struct {
+		Starting int64 `json:"starting"`
+		Ending   int64 `json:"ending"`
+		Current  int64 `json:"current"`
+	} `json:"votingPeriod"`
+	Consensus json.RawMessage `json:"consensus,omitempty"`
+	Votes     json.RawMessage `json:"votes,omitempty"`
+}
+
+// proposalsHandler returns the proposals, only those in the ?state= if it is set
+func proposalsHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		state := r.URL.Query().Get("state")
+
+		if state != "" && !proposalStates[state] {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = fmt.Sprintf("state must be one of pending, accepted, rejected or expired")
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		proposals, err := getProposals(coinData, state)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Proposals error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = proposals
+
+		apiResp.Send(w)
+
+	})
+}
+
+// proposalHandler returns the proposal for the hash in the route
+func proposalHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		proposal, err := getProposal(coinData, mux.Vars(r)["hash"])
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Proposal error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = proposal
+
+		apiResp.Send(w)
+
+	})
+}
+
+// paymentRequestHandler returns the payment request for the hash in the route
+func paymentRequestHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		paymentRequest, err := getPaymentRequest(coinData, mux.Vars(r)["hash"])
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Payment request error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = paymentRequest
+
+		apiResp.Send(w)
+
+	})
+}
+
+// daoStatsHandler returns the fund's balances and voting period
+func daoStatsHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		stats, err := getDAOStats(coinData)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Community Fund stats error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = stats
+
+		apiResp.Send(w)
+
+	})
+}
+
+// getProposals returns the proposals in the state, or all of them if state is empty
+func getProposals(coinData conf.CoinData, state string) ([]Proposal, error) {
+
+	proposals := []Proposal{}
+
+	params := []interface{}{}
+	if state != "" {
+		params = append(params, state)
+	}
+
+	err := callDAO(coinData, "listproposals", params, &proposals, func() error {
+		for i := range proposals {
+			if err := setDeadline(coinData, &proposals[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return proposals, err
+
+}
+
+// getProposal returns the proposal with the hash
+func getProposal(coinData conf.CoinData, hash string) (Proposal, error) {
+
+	proposal := Proposal{}
+
+	err := callDAO(coinData, "getproposal", []interface{}{hash}, &proposal, func() error {
+		return setDeadline(coinData, &proposal)
+	})
+
+	return proposal, err
+
+}
+
+// getPaymentRequest returns the payment request with the hash
+func getPaymentRequest(coinData conf.CoinData, hash string) (PaymentRequest, error) {
+
+	paymentRequest := PaymentRequest{}
+
+	err := callDAO(coinData, "getpaymentrequest", []interface{}{hash}, &paymentRequest, nil)
+
+	return paymentRequest, err
+
+}
+
+// getDAOStats returns the decoded 'cfundstats' result
+func getDAOStats(coinData conf.CoinData) (DAOStats, error) {
+
+	stats := DAOStats{}
+
+	err := callDAO(coinData, "cfundstats", nil, &stats, nil)
+
+	return stats, err
+
+}
+
+// callDAO decodes the result of the Community Fund RPC call into result,
+// completing it with complete, and caches it until the next block
+func callDAO(coinData conf.CoinData, method string, params []interface{}, result interface{}, complete func() error) error {
+
+	bestHash := ""
+
+	if err := daemonrpc.CallDaemon(coinData, "getbestblockhash", nil, &bestHash); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s:%s:%s:%v", coinData.CurrencyCode, bestHash, method, params)
+
+	if daoCache.Get(key, result) {
+		return nil
+	}
+
+	if err := daemonrpc.CallDaemon(coinData, method, params, result); err != nil {
+		return err
+	}
+
+	if complete != nil {
+		if err := complete(); err != nil {
+			return err
+		}
+	}
+
+	return daoCache.Set(key, result)
+
+}
+
+// setDeadline sets when the proposal expires from the time of the block that included it
+func setDeadline(coinData conf.CoinData, proposal *Proposal) error {
+
+	if proposal.BlockHash == "" {
+		return nil
+	}
+
+	block, err := getBlock(coinData, proposal.BlockHash)
+
+	if err != nil {
+		return err
+	}
+
+	proposal.Deadline = block.Time + proposal.ProposalDuration
+
+	return nil
+
+}
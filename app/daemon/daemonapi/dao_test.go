@@ -0,0 +1,166 @@
+package daemonapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+// mock data struct for list proposals response
+func mockListProposalsResponseData() string {
+	return `{"result": [{
+  "version": 2,
+  "hash": "8f1a6a32ed1b2cf3b8b0ba9e3c4b5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e",
+  "blockHash": "52260690630225abb5b9bd1f9b72774ced5f9b74e18ac2ab7dd5b76d229fbfdd",
+  "description": "Website redesign",
+  "requestedAmount": "1000.00000000",
+  "notPaidYet": "400.00000000",
+  "userPaidFee": "50.00000000",
+  "paymentAddress": "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G",
+  "proposalDuration": 604800,
+  "votesYes": 12,
+  "votesNo": 3,
+  "votingCycle": 1,
+  "status": "accepted",
+  "state": 1,
+  "stateChangedOnBlock": "4f5d6b2b9c5c0e1b6e4a3d2c1b0a99887766554433221100ffeeddccbbaa9988",
+  "paymentRequests": [{
+    "version": 2,
+    "hash": "d2f9d4a5f0e9c4e10e1c1a09c6b1a1f4c2e3d5b6a7f8091a2b3c4d5e6f708192",
+    "blockHash": "52260690630225abb5b9bd1f9b72774ced5f9b74e18ac2ab7dd5b76d229fbfdd",
+    "description": "First milestone",
+    "requestedAmount": "600.00000000",
+    "votesYes": 20,
+    "votesNo": 1,
+    "votingCycle": 2,
+    "status": "paid",
+    "state": 1,
+    "paidOnBlock": "4f5d6b2b9c5c0e1b6e4a3d2c1b0a99887766554433221100ffeeddccbbaa9988"
+  }]
+}], "error": null, "id": null}`
+}
+
+// mock data struct for cfundstats response
+func mockCFundStatsResponseData() string {
+	return `{"result": {
+  "funds": {"available": 12345.6789, "locked": 400},
+  "votingPeriod": {"starting": 20160, "ending": 40320, "current": 30000},
+  "consensus": {"blocksPerVotingCycle": 20160}
+}, "error": null, "id": null}`
+}
+
+// test that coin values are decoded to satoshis from numbers and strings
+func Test_Amount_UnmarshalJSON(t *testing.T) {
+
+	amounts := []Amount{}
+
+	err := json.Unmarshal([]byte(`[1.5, "0.00000001", "1000.00000000", 0]`), &amounts)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Amount{150000000, 1, 100000000000, 0}, amounts)
+
+	err = json.Unmarshal([]byte(`["lots"]`), &amounts)
+	assert.NotNil(t, err)
+
+}
+
+// test that proposals are typed and given a deadline
+func Test_getProposals(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	daoCache.Purge()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getbestblockhash": mockGetBlockHashResponseData(),
+			"listproposals":    mockListProposalsResponseData(),
+			"getblock":         mockGetBlockResponseData(),
+		}))
+
+	proposals, err := getProposals(rpctest.CoinData(), "accepted")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(proposals))
+	assert.Equal(t, Amount(100000000000), proposals[0].RequestedAmount)
+	assert.Equal(t, Amount(40000000000), proposals[0].NotPaidYet)
+	assert.Equal(t, int64(1463088112+604800), proposals[0].Deadline)
+	assert.Equal(t, int64(12), proposals[0].VotesYes)
+	assert.Equal(t, "accepted", proposals[0].Status)
+	assert.Equal(t, 1, len(proposals[0].PaymentRequests))
+	assert.Equal(t, Amount(60000000000), proposals[0].PaymentRequests[0].RequestedAmount)
+	assert.Equal(t, "paid", proposals[0].PaymentRequests[0].Status)
+
+}
+
+// test that responses are cached until the best block changes
+func Test_getProposals_cached(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	daoCache.Purge()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getbestblockhash": mockGetBlockHashResponseData(),
+			"listproposals":    mockListProposalsResponseData(),
+			"getblock":         mockGetBlockResponseData(),
+		}))
+
+	_, err := getProposals(rpctest.CoinData(), "")
+	assert.Nil(t, err)
+
+	// the list is served from the cache for the same best block
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getbestblockhash": mockGetBlockHashResponseData(),
+			"listproposals":    `{"result": [], "error": null, "id": null}`,
+		}))
+
+	proposals, err := getProposals(rpctest.CoinData(), "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(proposals))
+
+	// a new block fetches the list again
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getbestblockhash": `{"result": "4f5d6b2b9c5c0e1b6e4a3d2c1b0a99887766554433221100ffeeddccbbaa9988", "error": null, "id": null}`,
+			"listproposals":    `{"result": [], "error": null, "id": null}`,
+		}))
+
+	proposals, err = getProposals(rpctest.CoinData(), "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(proposals))
+
+}
+
+// test that the fund stats are decoded
+func Test_getDAOStats(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	daoCache.Purge()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getbestblockhash": mockGetBlockHashResponseData(),
+			"cfundstats":       mockCFundStatsResponseData(),
+		}))
+
+	stats, err := getDAOStats(rpctest.CoinData())
+
+	assert.Nil(t, err)
+	assert.Equal(t, Amount(1234567890000), stats.Funds.Available)
+	assert.Equal(t, Amount(40000000000), stats.Funds.Locked)
+	assert.Equal(t, int64(40320), stats.VotingPeriod.Ending)
+	assert.JSONEq(t, `{"blocksPerVotingCycle": 20160}`, string(stats.Consensus))
+
+}
@@ -0,0 +1,231 @@
+package daemonapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/gorilla/mux"
+)
+
+// InitExplorerHandlers sets up handlers for block and transaction lookups
+func InitExplorerHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "explorer"
+
+	// block endpoint :: provides a block and its txids by height or hash
+	blockPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "block/{id}")
+	api.OpenRouteHandler(blockPath, r, blockHandler(coinData))
+
+	// tx endpoint :: provides a single normalised transaction by txid
+	txPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "tx/{txid}")
+	api.OpenRouteHandler(txPath, r, txHandler(coinData))
+
+	// tip endpoint :: provides a summary of the current chain tip
+	tipPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "tip")
+	api.OpenRouteHandler(tipPath, r, tipHandler(coinData))
+
+}
+
+// Block is the decoded result of a 'getblock' RPC call
+type Block struct {
+	Hash              string   `json:"hash"`
+	Confirmations     int64    `json:"confirmations"`
+	Size              int      `json:"size"`
+	Height            int64    `json:"height"`
+	Version           int      `json:"version"`
+	MerkleRoot        string   `json:"merkleroot"`
+	Tx                []string `json:"tx"`
+	Time              int64    `json:"time"`
+	MedianTime        int64    `json:"mediantime"`
+	Nonce             uint32   `json:"nonce"`
+	Bits              string   `json:"bits"`
+	Difficulty        float64  `json:"difficulty"`
+	ChainWork         string   `json:"chainwork"`
+	PreviousBlockHash string   `json:"previousblockhash,omitempty"`
+	NextBlockHash     string   `json:"nextblockhash,omitempty"`
+}
+
+// ChainInfo is the decoded result of a 'getblockchaininfo' RPC call
+type ChainInfo struct {
+	Chain                string  `json:"chain"`
+	Blocks               int64   `json:"blocks"`
+	Headers              int64   `json:"headers"`
+	BestBlockHash        string  `json:"bestblockhash"`
+	Difficulty           float64 `json:"difficulty"`
+	MedianTime           int64   `json:"mediantime"`
+	VerificationProgress float64 `json:"verificationprogress"`
+}
+
+// ChainTip summarises the current tip of the chain
+type ChainTip struct {
+	Chain                string  `json:"chain"`
+	Height               int64   `json:"height"`
+	Headers              int64   `json:"headers"`
+	Hash                 string  `json:"hash"`
+	Time                 int64   `json:"time"`
+	MedianTime           int64   `json:"mediantime"`
+	Difficulty           float64 `json:"difficulty"`
+	VerificationProgress float64 `json:"verificationprogress"`
+	TxCount              int     `json:"txcount"`
+}
+
+// blockHandler returns the block for the height or hash in the route
+func blockHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		block, err := getBlockByID(coinData, mux.Vars(r)["id"])
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Block error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = block
+
+		apiResp.Send(w)
+
+	})
+}
+
+// txHandler returns the normalised transaction for the txid in the route
+// the raw transaction hex is only included when ?raw=true is supplied
+func txHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		tx, err := getTransaction(coinData, mux.Vars(r)["txid"], "", newChainTip(coinData))
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Transaction error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if r.URL.Query().Get("raw") != "true" {
+			tx.RawTx = ""
+		}
+
+		apiResp.Data = tx
+
+		apiResp.Send(w)
+
+	})
+}
+
+// tipHandler returns a summary of the current chain tip
+func tipHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		tip, err := getChainTip(coinData)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Chain tip error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = tip
+
+		apiResp.Send(w)
+
+	})
+}
+
+// getBlockByID treats a numeric id as a block height and anything else as a block hash
+func getBlockByID(coinData conf.CoinData, id string) (Block, error) {
+
+	hash := id
+
+	if height, err := strconv.ParseInt(id, 10, 64); err == nil {
+
+		hash, err = getBlockHash(coinData, height)
+
+		if err != nil {
+			return Block{}, err
+		}
+
+	}
+
+	return getBlock(coinData, hash)
+
+}
+
+// getBlockHash takes a height and returns the hash of the block in the active chain
+func getBlockHash(coinData conf.CoinData, height int64) (string, error) {
+
+	hash := ""
+
+	err := daemonrpc.CallDaemon(coinData, "getblockhash", []interface{}{height}, &hash)
+
+	return hash, err
+
+}
+
+// getBlock takes a block hash and returns the decoded block
+func getBlock(coinData conf.CoinData, hash string) (Block, error) {
+
+	block := Block{}
+
+	err := daemonrpc.CallDaemon(coinData, "getblock", []interface{}{hash}, &block)
+
+	return block, err
+
+}
+
+// getChainInfo returns the decoded 'getblockchaininfo' result
+func getChainInfo(coinData conf.CoinData) (ChainInfo, error) {
+
+	info := ChainInfo{}
+
+	err := daemonrpc.CallDaemon(coinData, "getblockchaininfo", nil, &info)
+
+	return info, err
+
+}
+
+// getChainTip combines the chain info with the best block to summarise the tip
+func getChainTip(coinData conf.CoinData) (ChainTip, error) {
+
+	info, err := getChainInfo(coinData)
+
+	if err != nil {
+		return ChainTip{}, err
+	}
+
+	block, err := getBlock(coinData, info.BestBlockHash)
+
+	if err != nil {
+		return ChainTip{}, err
+	}
+
+	tip := ChainTip{
+		Chain:                info.Chain,
+		Height:               info.Blocks,
+		Headers:              info.Headers,
+		Hash:                 info.BestBlockHash,
+		Time:                 block.Time,
+		MedianTime:           info.MedianTime,
+		Difficulty:           info.Difficulty,
+		VerificationProgress: info.VerificationProgress,
+		TxCount:              len(block.Tx),
+	}
+
+	return tip, nil
+
+}
@@ -0,0 +1,110 @@
+package daemonapi
+
+import (
+	"testing"
+
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+// mock data struct for get block response
+func mockGetBlockResponseData() string {
+	return `{"result": {
+  "hash": "52260690630225abb5b9bd1f9b72774ced5f9b74e18ac2ab7dd5b76d229fbfdd",
+  "confirmations": 55769,
+  "height": 523,
+  "time": 1463088112,
+  "tx": [
+    "d2f9d4a5f0e9c4e10e1c1a09c6b1a1f4c2e3d5b6a7f8091a2b3c4d5e6f708192",
+    "c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929"
+  ],
+  "previousblockhash": "4f5d6b2b9c5c0e1b6e4a3d2c1b0a99887766554433221100ffeeddccbbaa9988"
+}, "error": null, "id": null}`
+}
+
+// mock data struct for get block hash response
+func mockGetBlockHashResponseData() string {
+	return `{"result": "52260690630225abb5b9bd1f9b72774ced5f9b74e18ac2ab7dd5b76d229fbfdd", "error": null, "id": null}`
+}
+
+// mock data struct for get blockchain info response
+func mockGetBlockchainInfoResponseData() string {
+	return `{"result": {
+  "chain": "main",
+  "blocks": 523,
+  "headers": 530,
+  "bestblockhash": "52260690630225abb5b9bd1f9b72774ced5f9b74e18ac2ab7dd5b76d229fbfdd",
+  "difficulty": 1.5,
+  "mediantime": 1463088000,
+  "verificationprogress": 0.99
+}, "error": null, "id": null}`
+}
+
+// test that a numeric id is looked up as a height
+func Test_getBlockByID_height(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getblockhash": mockGetBlockHashResponseData(),
+			"getblock":     mockGetBlockResponseData(),
+		}))
+
+	block, err := getBlockByID(rpctest.CoinData(), "523")
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(523), block.Height)
+	assert.Equal(t, "52260690630225abb5b9bd1f9b72774ced5f9b74e18ac2ab7dd5b76d229fbfdd", block.Hash)
+	assert.Equal(t, 2, len(block.Tx))
+
+}
+
+// test that a daemon error is returned for an unknown block hash
+func Test_getBlockByID_unknownHash(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getblock": `{"result": null, "error": {"code": -5, "message": "Block not found"}, "id": null}`,
+		}))
+
+	_, err := getBlockByID(rpctest.CoinData(), "ffff")
+
+	assert.NotNil(t, err)
+
+	rpcErr, ok := err.(*daemonrpc.RPCError)
+
+	assert.True(t, ok)
+	assert.Equal(t, -5, rpcErr.Code)
+	assert.Equal(t, "Block not found", rpcErr.Message)
+
+}
+
+// test that the tip combines chain info and the best block
+func Test_getChainTip(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getblockchaininfo": mockGetBlockchainInfoResponseData(),
+			"getblock":          mockGetBlockResponseData(),
+		}))
+
+	tip, err := getChainTip(rpctest.CoinData())
+
+	assert.Nil(t, err)
+	assert.Equal(t, "main", tip.Chain)
+	assert.Equal(t, int64(523), tip.Height)
+	assert.Equal(t, int64(530), tip.Headers)
+	assert.Equal(t, int64(1463088112), tip.Time)
+	assert.Equal(t, 2, tip.TxCount)
+
+}
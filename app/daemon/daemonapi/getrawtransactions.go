@@ -6,10 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"io/ioutil"
-
+	"github.com/Encrypt-S/kauri-api/app/address"
 	"github.com/Encrypt-S/kauri-api/app/api"
 	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonhistory"
 	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
 	"github.com/gorilla/mux"
 )
@@ -42,16 +42,11 @@ type AddressTransactions struct {
 	Transactions []Transaction `json:"transactions"`
 }
 
-// Transaction contains txid, raw transactions txid, verbose boolean
-type Transaction struct {
-	TxID    string      `json:"txid"`
-	RawTx   string      `json:"rawtx"`
-	Verbose interface{} `json:"verbose"`
-}
-
 // IncomingTransactions are the incoming transactions in POST body
+// raw transaction hex is only returned when Raw is requested
 type IncomingTransactions struct {
 	IncomingTxItems []WalletItem `json:"transactions"`
+	Raw             bool         `json:"raw"`
 }
 
 // WalletItem is the incoming currency and corresponding addresses
@@ -61,8 +56,11 @@ type WalletItem struct {
 }
 
 // GetTxIDParams are the addresses array params for 'getaddresstxids' RPC call
+// Start and End limit the txids to a range of block heights when set
 type GetTxIDParams struct {
 	Addresses []string `json:"addresses"`
+	Start     int64    `json:"start,omitempty"`
+	End       int64    `json:"end,omitempty"`
 }
 
 // GetTxIDsResp is the Result of RPC response > txid (array)
@@ -70,9 +68,20 @@ type GetTxIDsResp struct {
 	Result []string `json:"result"`
 }
 
-// GetRawTxResp is the Result of RPC response > raw (string)
-type GetRawTxResp struct {
-	Result string `json:"result"`
+// GetMempoolResp is the Result of RPC response > mempool entries (array)
+type GetMempoolResp struct {
+	Result []MempoolEntry `json:"result"`
+}
+
+// MempoolEntry is a single 'getaddressmempool' delta for an address
+type MempoolEntry struct {
+	Address   string `json:"address"`
+	TxID      string `json:"txid"`
+	Index     int    `json:"index"`
+	Satoshis  int64  `json:"satoshis"`
+	Timestamp int64  `json:"timestamp"`
+	PrevTxID  string `json:"prevtxid,omitempty"`
+	PrevOut   int    `json:"prevout,omitempty"`
 }
 
 // getRawTxHandler ranges through transactions, returns RPC response data
@@ -93,6 +102,17 @@ func getRawTxHandler(coinData conf.CoinData) http.Handler {
 			return
 		}
 
+		// reject invalid addresses before making any daemon requests
+		err = validateAddresses(coinData, incomingTxs)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.InvalidAddress
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
 		resp, err := buildResponse(coinData, incomingTxs)
 
 		if err != nil {
@@ -111,6 +131,27 @@ func getRawTxHandler(coinData conf.CoinData) http.Handler {
 	})
 }
 
+// validateAddresses checks every incoming address of the coin's currency
+func validateAddresses(coinData conf.CoinData, incomingAddreses IncomingTransactions) error {
+
+	for _, item := range incomingAddreses.IncomingTxItems {
+
+		if item.Currency != coinData.CurrencyCode || !address.IsSupported(item.Currency) {
+			continue
+		}
+
+		for _, addressStr := range item.Addresses {
+			if err := address.Validate(item.Currency, addressStr, coinData.UseTestNet); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+
+}
+
 // buildResponse takes address and returns response data
 func buildResponse(coinData conf.CoinData, incomingAddreses IncomingTransactions) (TxResponse, error) {
 
@@ -126,7 +167,17 @@ func buildResponse(coinData conf.CoinData, incomingAddreses IncomingTransactions
 			result.Currency = "NAV"
 
 			// get transaction related to the address and store them in the result
-			result.Addresses, _ = getTxForAddresses(coinData, item.Addresses)
+			addresses, err := getTxForAddresses(coinData, item.Addresses)
+
+			if err != nil {
+				return TxResponse{}, err
+			}
+
+			result.Addresses = addresses
+
+			if !incomingAddreses.Raw {
+				stripRawTx(result.Addresses)
+			}
 
 			// append the result to the array of results
 			resp.Results = append(resp.Results, result)
@@ -144,36 +195,41 @@ func getTxForAddresses(coinData conf.CoinData, addresses []string) ([]AddressTra
 
 	adds := []AddressTransactions{}
 
+	// every address's cached transactions share one chain height
+	tip := newChainTip(coinData)
+
 	for _, addressStr := range addresses {
 
 		addStruct := AddressTransactions{}
 		addStruct.Address = addressStr
-		rpcTxIDsResp, err := getTxIdsRPC(coinData, addressStr)
+		txIDs, err := getConfirmedTxIDs(coinData, addressStr, 0, 0)
 
 		if err != nil {
 			return nil, err
 		}
 
-		// for all the txIDs from the rpc we need to create a transaction
-		for _, txID := range rpcTxIDsResp.Result {
+		// for all the confirmed txIDs we need to create a transaction
+		for _, txID := range txIDs {
 
-			rawTx, _ := getRawTx(coinData, txID)
+			trans, err := getTransaction(coinData, txID, addressStr, tip)
 
 			if err != nil {
 				return nil, err
 			}
 
-			verboseTx, _ := getRawTxVerbose(coinData, txID)
+			addStruct.Transactions = append(addStruct.Transactions, trans)
 
-			if err != nil {
-				return nil, err
-			}
+		}
 
-			trans := Transaction{TxID: txID, RawTx: rawTx.Result, Verbose: verboseTx}
-			addStruct.Transactions = append(addStruct.Transactions, trans)
+		// pending transactions are not returned by getaddresstxids so add them from the mempool
+		pending, err := getMempoolTxForAddress(coinData, addressStr, txIDs, tip)
 
+		if err != nil {
+			return nil, err
 		}
 
+		addStruct.Transactions = append(addStruct.Transactions, pending...)
+
 		adds = append(adds, addStruct)
 
 	}
@@ -181,11 +237,61 @@ func getTxForAddresses(coinData conf.CoinData, addresses []string) ([]AddressTra
 	return adds, nil
 }
 
+// stripRawTx removes the raw transaction hex from the address transactions
+func stripRawTx(adds []AddressTransactions) {
+	for i := range adds {
+		for j := range adds[i].Transactions {
+			adds[i].Transactions[j].RawTx = ""
+		}
+	}
+}
+
+// getConfirmedTxIDs returns the address's confirmed txids from the coin's local
+// history store if it has one, otherwise from the daemon's address index
+// a non zero end limits them to the blocks from start to end
+func getConfirmedTxIDs(coinData conf.CoinData, address string, start int64, end int64) ([]string, error) {
+
+	if store := daemonhistory.Get(coinData.CurrencyCode); store != nil {
+
+		entries, err := store.History(address)
+
+		if err == nil {
+			txIDs := []string{}
+			for _, entry := range entries {
+				if end == 0 || (entry.Height >= start && entry.Height <= end) {
+					txIDs = append(txIDs, entry.TxID)
+				}
+			}
+			return txIDs, nil
+		}
+
+		// until the store has synced its first tip the daemon is asked
+		if err != daemonhistory.ErrNotSynced {
+			return nil, err
+		}
+
+	}
+
+	rpcTxIDsResp, err := getTxIdsRPC(coinData, address, start, end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rpcTxIDsResp.Result, nil
+
+}
+
 // getTxIdsRPC takes address and returns array of txids
-func getTxIdsRPC(coinData conf.CoinData, address string) (GetTxIDsResp, error) {
+// a non zero end limits them to the blocks from start to end
+func getTxIdsRPC(coinData conf.CoinData, address string, start int64, end int64) (GetTxIDsResp, error) {
 
 	getParams := GetTxIDParams{}
 
+	if end != 0 {
+		getParams.Start, getParams.End = start, end
+	}
+
 	getParams.Addresses = append(getParams.Addresses, address)
 
 	reqData := daemonrpc.RPCRequestData{}
@@ -210,52 +316,80 @@ func getTxIdsRPC(coinData conf.CoinData, address string) (GetTxIDsResp, error) {
 
 }
 
-// getRawTx takes txid and returns raw transaction data
-func getRawTx(coinData conf.CoinData, txid string) (GetRawTxResp, error) {
+// getMempoolTxForAddress builds unconfirmed transactions for the address
+// from its mempool entries, skipping any txids that have already confirmed
+func getMempoolTxForAddress(coinData conf.CoinData, address string, confirmed []string, tip *chainTip) ([]Transaction, error) {
 
-	rpcData := daemonrpc.RPCRequestData{}
-	rpcData.Method = "getrawtransaction"
-	rpcData.Params = []string{txid}
+	rpcMempoolResp, err := getMempoolRPC(coinData, address)
 
-	resp, err := daemonrpc.RequestDaemon(coinData, rpcData, conf.DaemonConf)
 	if err != nil {
-		return GetRawTxResp{}, err
+		return nil, err
 	}
 
-	bodyBytes, _ := ioutil.ReadAll(resp.Body)
-	var rawData map[string]string
+	seen := make(map[string]bool)
+	for _, txID := range confirmed {
+		seen[txID] = true
+	}
+
+	// the mempool returns one entry per input/output so keep the earliest
+	// arrival time for each txid, in the order they were first seen
+	txIDs := []string{}
+	arrivals := make(map[string]int64)
+
+	for _, entry := range rpcMempoolResp.Result {
+
+		if seen[entry.TxID] {
+			continue
+		}
+
+		arrival, ok := arrivals[entry.TxID]
+
+		if !ok {
+			txIDs = append(txIDs, entry.TxID)
+		}
+
+		if !ok || entry.Timestamp < arrival {
+			arrivals[entry.TxID] = entry.Timestamp
+		}
 
-	if err := json.Unmarshal(bodyBytes, &rawData); err != nil {
-		return GetRawTxResp{}, err
 	}
 
-	rawResp := GetRawTxResp{}
-	rawResp.Result = rawData["result"]
+	txs := []Transaction{}
+
+	for _, txID := range txIDs {
+
+		trans, err := getTransaction(coinData, txID, address, tip)
 
-	return rawResp, nil
+		if err != nil {
+			return nil, err
+		}
+
+		trans.Unconfirmed = true
+		trans.Time = arrivals[txID]
+		txs = append(txs, trans)
+
+	}
+
+	return txs, nil
 
 }
 
-// getRawTxVerbose takes txid and returns verbose transaction data
-func getRawTxVerbose(coinData conf.CoinData, txid string) (interface{}, error) {
+// getMempoolRPC takes address and returns its pending mempool entries
+func getMempoolRPC(coinData conf.CoinData, address string) (GetMempoolResp, error) {
 
-	rpcData := daemonrpc.RPCRequestData{}
-	rpcData.Method = "getrawtransaction"
-	rpcData.Params = []interface{}{txid, 1}
+	getParams := GetTxIDParams{}
 
-	resp, err := daemonrpc.RequestDaemon(coinData, rpcData, conf.DaemonConf)
+	getParams.Addresses = append(getParams.Addresses, address)
 
-	if err != nil {
-		return nil, err
-	}
+	rpcMempoolResults := GetMempoolResp{}
 
-	bodyBytes, _ := ioutil.ReadAll(resp.Body)
-	var data map[string]interface{}
+	// called through CallDaemon so an RPC error isn't read as an empty mempool
+	err := daemonrpc.CallDaemon(coinData, "getaddressmempool", []interface{}{getParams}, &rpcMempoolResults.Result)
 
-	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		return nil, err
+	if err != nil {
+		return GetMempoolResp{}, err
 	}
 
-	return data["result"], nil
+	return rpcMempoolResults, nil
 
 }
@@ -3,19 +3,14 @@ package daemonapi
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"testing"
 
-	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/jarcoal/httpmock.v1"
 )
 
-func mockCoinData() conf.CoinData {
-	data := conf.CoinData{}
-	data.CurrencyCode = "NAV"
-	return data
-}
-
 // mock data struct for get txids response
 func mockGetTxIdsResponseData() string {
 	return `{"result":[
@@ -28,16 +23,39 @@ func mockGetTxIdsResponseData() string {
 ]}`
 }
 
-// mock data struct for get raw tx response
-func mockGetRawTxResponseData() string {
-	return `{"result": "123",
-"error": null,
-"id": null}`
+// mock data struct for get address mempool response
+func mockGetMempoolResponseData() string {
+	return `{"result":[
+  {"address": "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", "txid": "11a7071a43a8da2b9ac116865a6cd92c985c3f7cbde63933d253f88dffaa311a", "index": 1, "satoshis": 100000000, "timestamp": 1530000000},
+  {"address": "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", "txid": "f3f2c1a1f61e9c8b1e0a4e1b3d6f5a2c9e0b7d4a1c8f5e2b9d6a3c0f7e4b1a88", "index": 0, "satoshis": 250000000, "timestamp": 1530000200},
+  {"address": "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", "txid": "f3f2c1a1f61e9c8b1e0a4e1b3d6f5a2c9e0b7d4a1c8f5e2b9d6a3c0f7e4b1a88", "index": 0, "satoshis": -50000000, "timestamp": 1530000100, "prevtxid": "c6b6063a0512ed40958bff62a48168b4b30f89cb6bce22b722f8a6d00fcb9d98", "prevout": 1}
+]}`
 }
 
 // mock data struct for get raw tx verbose response
 func mockGetRawTxVerboseResponseData() string {
-	return `{"result": "123"}`
+	return `{"result": {
+  "txid": "{{txid}}",
+  "hex": "01000000f0f33457011a31aaff8df853d23339e6bd7c3f5c982cd96c5a8616c19a2bdaa8431a07a711010000006a47304402202fbb2c5955013fc4806420a66e5c9116902c0263fe7920ae104ff1818ef62efd022040857e3108ae8f30e8a0800f8f892c8a97aa88b67b8e40032e2ba33d3445230e012103f6c3b8154a19327783dd46e0dda13f812f57b00f9246387f62d5ece8bed767b4ffffffff0300000000000000000000debdfcc1c60100232103f6c3b8154a19327783dd46e0dda13f812f57b00f9246387f62d5ece8bed767b4ac3688d6fcc1c60100232103f6c3b8154a19327783dd46e0dda13f812f57b00f9246387f62d5ece8bed767b4ac00000000",
+  "blockhash": "52260690630225abb5b9bd1f9b72774ced5f9b74e18ac2ab7dd5b76d229fbfdd",
+  "blocktime": 1463088112,
+  "confirmations": 55769,
+  "height": 523,
+  "time": 1463088112,
+  "vin": [
+    {"txid": "11a7071a43a8da2b9ac116865a6cd92c985c3f7cbde63933d253f88dffaa311a", "vout": 1, "sequence": 4294967295}
+  ],
+  "vout": [
+    {"n": 0, "value": 0, "valueSat": 0, "scriptPubKey": {"asm": "", "hex": "", "type": "nonstandard"}},
+    {"n": 1, "value": 5000114.48, "valueSat": 500011448000000, "scriptPubKey": {"addresses": ["NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"], "type": "pubkey"}},
+    {"n": 2, "value": 5000114.49616438, "scriptPubKey": {"addresses": ["NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"], "type": "pubkey"}}
+  ]
+}}`
+}
+
+// mock data struct for get block count response
+func mockGetBlockCountResponseData(height int) string {
+	return fmt.Sprintf(`{"result": %d, "error": null}`, height)
 }
 
 // mock out the data struct for incoming POST body
@@ -67,6 +85,26 @@ func setupIncomingTestData(t *testing.T) IncomingTransactions {
 	return incomingAddressesReq
 }
 
+// test that a failed daemon call is returned rather than an empty history
+func Test_buildResponse_error(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getaddresstxids":   mockGetTxIdsResponseData(),
+			"getaddressmempool": `{"result": null, "error": {"code": -5, "message": "No information available for address"}}`,
+			"getrawtransaction": mockGetRawTxVerboseResponseData(),
+			"getblockcount":     mockGetBlockCountResponseData(56291),
+		}))
+
+	_, err := buildResponse(rpctest.CoinData(), setupIncomingTestData(t))
+
+	assert.NotNil(t, err)
+
+}
+
 // Test_buildResponse tests the main buildResponse function
 func Test_buildResponse(t *testing.T) {
 
@@ -74,12 +112,19 @@ func Test_buildResponse(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
-		httpmock.NewStringResponder(200, mockGetTxIdsResponseData()))
+		rpctest.Responder(map[string]string{
+			"getaddresstxids":   mockGetTxIdsResponseData(),
+			"getaddressmempool": `{"result":[]}`,
+			"getrawtransaction": mockGetRawTxVerboseResponseData(),
+			"getblockcount":     mockGetBlockCountResponseData(56291),
+		}))
 
 	incomingAddreses := setupIncomingTestData(t)
-	coinData := mockCoinData()
+	coinData := rpctest.CoinData()
+
+	resp, err := buildResponse(coinData, incomingAddreses)
 
-	resp, _ := buildResponse(coinData, incomingAddreses)
+	assert.Nil(t, err)
 
 	// check that we have only nav currencies
 	for i := range resp.Results {
@@ -96,6 +141,24 @@ func Test_buildResponse(t *testing.T) {
 
 }
 
+// test that only invalid addresses of the coin's currency are rejected
+func Test_validateAddresses(t *testing.T) {
+
+	incomingAddreses := setupIncomingTestData(t)
+	coinData := rpctest.CoinData()
+
+	// the BTC addresses are not ours to validate
+	assert.Nil(t, validateAddresses(coinData, incomingAddreses))
+
+	incomingAddreses.IncomingTxItems[0].Addresses[1] = "NUDke42E3fwLqaBbBFRyVSTETuhWAi7ugj"
+
+	err := validateAddresses(coinData, incomingAddreses)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "NUDke42E3fwLqaBbBFRyVSTETuhWAi7ugj")
+
+}
+
 // test the get transactions function
 func Test_getTransactionsForAddress(t *testing.T) {
 
@@ -103,10 +166,15 @@ func Test_getTransactionsForAddress(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
-		httpmock.NewStringResponder(200, mockGetTxIdsResponseData()))
+		rpctest.Responder(map[string]string{
+			"getaddresstxids":   mockGetTxIdsResponseData(),
+			"getaddressmempool": `{"result":[]}`,
+			"getrawtransaction": mockGetRawTxVerboseResponseData(),
+			"getblockcount":     mockGetBlockCountResponseData(56291),
+		}))
 
 	incomingAddresses := setupIncomingTestData(t)
-	coinData := mockCoinData()
+	coinData := rpctest.CoinData()
 
 	adds, _ := getTxForAddresses(coinData, incomingAddresses.IncomingTxItems[0].Addresses)
 
@@ -132,45 +200,174 @@ func Test_getTxIDsRPC(t *testing.T) {
 	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
 		httpmock.NewStringResponder(200, mockGetTxIdsResponseData()))
 
-	coinData := mockCoinData()
+	coinData := rpctest.CoinData()
 
-	rpcResp, _ := getTxIdsRPC(coinData, "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G")
+	rpcResp, _ := getTxIdsRPC(coinData, "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", 0, 0)
 
 	assert.Equal(t, "11a7071a43a8da2b9ac116865a6cd92c985c3f7cbde63933d253f88dffaa311a", rpcResp.Result[0])
 	assert.Equal(t, "52489abff43212445d432f6042e5b9faf99b3c843a79210629b5383f52694ec5", rpcResp.Result[4])
 
 }
 
-// test that raw transaction data is returned from supplied txid
-func Test_getRawTx(t *testing.T) {
+// test that pending mempool transactions are grouped by txid and confirmed ones are skipped
+func Test_getMempoolTxForAddress(t *testing.T) {
 
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
-		httpmock.NewStringResponder(200, mockGetRawTxResponseData()))
+		rpctest.Responder(map[string]string{
+			"getaddressmempool": mockGetMempoolResponseData(),
+			"getrawtransaction": mockGetRawTxVerboseResponseData(),
+			"getblockcount":     mockGetBlockCountResponseData(56291),
+		}))
 
-	coinData := mockCoinData()
+	coinData := rpctest.CoinData()
+	confirmed := []string{"11a7071a43a8da2b9ac116865a6cd92c985c3f7cbde63933d253f88dffaa311a"}
 
-	rpcResp, _ := getRawTx(coinData, "11a7071a43a8da2b9ac116865a6cd92c985c3f7cbde63933d253f88dffaa311a")
+	txs, err := getMempoolTxForAddress(coinData, "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", confirmed, newChainTip(coinData))
 
-	assert.Equal(t, "123", rpcResp.Result)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, "f3f2c1a1f61e9c8b1e0a4e1b3d6f5a2c9e0b7d4a1c8f5e2b9d6a3c0f7e4b1a88", txs[0].TxID)
+	assert.True(t, txs[0].Unconfirmed)
+	assert.Equal(t, int64(1530000100), txs[0].Time)
 
 }
 
-// test that raw (verbose) transaction data is returned from supplied txid
+// test that verbose transaction data is decoded from supplied txid
 func Test_getVerboseTx(t *testing.T) {
 
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
-		httpmock.NewStringResponder(200, mockGetRawTxVerboseResponseData()))
+		rpctest.Responder(map[string]string{"getrawtransaction": mockGetRawTxVerboseResponseData()}))
+
+	coinData := rpctest.CoinData()
+
+	verboseTx, err := getVerboseTx(coinData, "c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929", verboseTx.TxID)
+	assert.Equal(t, int64(523), verboseTx.Height)
+	assert.Equal(t, 3, len(verboseTx.Vout))
+	assert.Equal(t, int64(500011448000000), verboseTx.Vout[1].Satoshis())
+	assert.Equal(t, int64(500011449616438), verboseTx.Vout[2].Satoshis())
+
+	// an unknown txid is an error, not an empty transaction
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{"getrawtransaction": `{"result": null, "error": {"code": -5, "message": "No information available about transaction"}}`}))
+
+	_, err = getVerboseTx(coinData, "c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929")
+
+	assert.EqualError(t, err, "daemon error -5: No information available about transaction")
+
+}
+
+// test that inputs are resolved from their previous transactions
+func Test_getTransaction(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getrawtransaction": mockGetRawTxVerboseResponseData(),
+			"getblockcount":     mockGetBlockCountResponseData(56291),
+		}))
+
+	coinData := rpctest.CoinData()
+
+	tx, err := getTransaction(coinData, "c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929", "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", newChainTip(coinData))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tx.Inputs))
+	assert.Equal(t, "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", tx.Inputs[0].Address)
+	assert.Equal(t, int64(500011448000000), tx.Inputs[0].Value)
+	assert.Equal(t, int64(523), tx.BlockHeight)
+	assert.Equal(t, int64(500011449616438), tx.Amount)
+	assert.Equal(t, DirectionIncoming, tx.Direction)
+	assert.False(t, tx.Unconfirmed)
+
+}
+
+// test that confirmed transactions are cached with their confirmations kept up to date
+func Test_getTransaction_cached(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	txCache.Purge()
+	outputsCache.Purge()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{"getrawtransaction": mockGetRawTxVerboseResponseData()}))
+
+	coinData := rpctest.CoinData()
+	txid := "c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929"
+
+	tx, err := getTransaction(coinData, txid, "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", newChainTip(coinData))
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(55769), tx.Confirmations)
+
+	// only the chain height is requested for a cached transaction
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{"getblockcount": mockGetBlockCountResponseData(56300)}))
+
+	hits := txCache.Stats().Hits
+	tip := newChainTip(coinData)
+
+	cached, err := getTransaction(coinData, txid, "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", tip)
+
+	assert.Nil(t, err)
+	assert.Equal(t, hits+1, txCache.Stats().Hits)
+	assert.Equal(t, int64(55778), cached.Confirmations)
+	assert.Equal(t, tx.Inputs, cached.Inputs)
+	assert.Equal(t, tx.Amount, cached.Amount)
+
+	// the request's later cache hits reuse its chain height
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", httpmock.NewErrorResponder(fmt.Errorf("unexpected call")))
+
+	cached, err = getTransaction(coinData, txid, "", tip)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(55778), cached.Confirmations)
+
+	// transactions without enough confirmations are not cached
+	txCache.Purge()
+	txCacheMinConfirmations = 60000
+	defer func() { txCacheMinConfirmations = defaultTxCacheMinConfirmations }()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{"getrawtransaction": mockGetRawTxVerboseResponseData()}))
+
+	getTransaction(coinData, txid, "", newChainTip(coinData))
+
+	assert.Equal(t, 0, txCache.Stats().Size)
+
+}
+
+// test the fee, direction and amount of a normalised transaction
+func Test_buildTransaction(t *testing.T) {
+
+	verboseTx := VerboseTx{TxID: "abc", Confirmations: 3}
+	verboseTx.Vout = []VerboseVout{
+		{N: 0, Value: 1.5, ScriptPubKey: ScriptPubKey{Addresses: []string{"receiver"}}},
+		{N: 1, ValueSat: 40000000, ScriptPubKey: ScriptPubKey{Addresses: []string{"sender"}}},
+	}
+	inputs := []TxInput{{TxID: "prev", Vout: 0, Address: "sender", Value: 200000000}}
+
+	sent := buildTransaction(rpctest.CoinData(), verboseTx, inputs, "sender")
 
-	coinData := mockCoinData()
+	assert.Equal(t, int64(10000000), sent.Fee)
+	assert.Equal(t, int64(-160000000), sent.Amount)
+	assert.Equal(t, DirectionOutgoing, sent.Direction)
 
-	rpcResp, _ := getRawTxVerbose(coinData, "11a7071a43a8da2b9ac116865a6cd92c985c3f7cbde63933d253f88dffaa311a")
+	received := buildTransaction(rpctest.CoinData(), verboseTx, inputs, "receiver")
 
-	assert.Equal(t, "123", rpcResp)
+	assert.Equal(t, int64(150000000), received.Amount)
+	assert.Equal(t, DirectionIncoming, received.Direction)
 
 }
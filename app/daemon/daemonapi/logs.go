@@ -0,0 +1,162 @@
+package daemonapi
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonlog"
+	"github.com/gorilla/mux"
+)
+
+// Logs served by the logs endpoint
+const (
+	LogOutput = "output"
+	LogDebug  = "debug"
+)
+
+// defaultLogLines and maxLogLines bound how many lines are returned
+const (
+	defaultLogLines = 100
+	maxLogLines     = 10000
+)
+
+// logFollowInterval is how often a followed log is checked for new lines
+var logFollowInterval = 500 * time.Millisecond
+
+// LogTail is the last lines of one of the daemon's logs
+type LogTail struct {
+	Log   string   `json:"log"`
+	Path  string   `json:"path"`
+	Lines []string `json:"lines"`
+}
+
+// InitLogHandlers sets up the protected handler tailing the coin's daemon output and debug.log
+func InitLogHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "daemon"
+
+	// logs endpoint :: the last lines of a log, followed as plain text with follow=true
+	logsPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "logs/{log}")
+	api.ProtectedRouteHandler(logsPath, r, logsHandler(coinData), http.MethodGet)
+
+}
+
+// logsHandler returns the last lines of the requested log, or streams it
+func logsHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		name := mux.Vars(r)["log"]
+
+		var path string
+		var err error
+
+		switch name {
+		case LogOutput:
+			path, err = daemon.OutputLogPath(coinData)
+		case LogDebug:
+			path, err = daemon.DebugLogPath(coinData)
+		default:
+			returnErr := api.AppRespErrors.NotFound
+			returnErr.ErrorMessage = "Log must be " + LogOutput + " or " + LogDebug
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if err != nil {
+			returnErr := api.AppRespErrors.ServerError
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		lines := defaultLogLines
+
+		if linesStr := r.URL.Query().Get("lines"); linesStr != "" {
+			lines, err = strconv.Atoi(linesStr)
+			if err != nil || lines < 0 || lines > maxLogLines {
+				returnErr := api.AppRespErrors.InvalidRequest
+				returnErr.ErrorMessage = "lines must be a number from 0 to " + strconv.Itoa(maxLogLines)
+				apiResp.Errors = append(apiResp.Errors, returnErr)
+				apiResp.Send(w)
+				return
+			}
+		}
+
+		tail, size, err := daemonlog.Tail(path, lines)
+
+		if os.IsNotExist(err) {
+			returnErr := api.AppRespErrors.NotFound
+			returnErr.ErrorMessage = "The " + coinData.CurrencyCode + " daemon has not written its " + name + " log yet"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if err != nil {
+			returnErr := api.AppRespErrors.ServerError
+			returnErr.ErrorMessage = "Failed to read the " + name + " log: " + err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if r.URL.Query().Get("follow") != "true" {
+			apiResp.Data = LogTail{Log: name, Path: path, Lines: tail}
+			apiResp.Send(w)
+			return
+		}
+
+		followLog(w, r, path, tail, size)
+
+	})
+}
+
+// followLog streams the tail and then each line appended to the log until the client goes away
+func followLog(w http.ResponseWriter, r *http.Request, path string, tail []string, offset int64) {
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	out := &flushWriter{w: w}
+
+	if len(tail) > 0 {
+		out.Write([]byte(strings.Join(tail, "\n") + "\n"))
+	} else {
+		out.flush()
+	}
+
+	daemonlog.Follow(path, offset, out, logFollowInterval, r.Context().Done())
+
+}
+
+// flushWriter sends each write to the client straight away
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+
+	n, err := f.w.Write(p)
+
+	f.flush()
+
+	return n, err
+
+}
+
+func (f *flushWriter) flush() {
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
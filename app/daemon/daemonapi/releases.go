@@ -0,0 +1,98 @@
+package daemonapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon"
+	"github.com/gorilla/mux"
+)
+
+// InitReleaseHandlers sets up the protected handlers listing the coin's daemon releases
+// and pinning the version or channel its upgrades use
+func InitReleaseHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "daemon"
+
+	// releases endpoint :: lists published releases and whether each is installed
+	releasesPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "releases")
+	api.ProtectedRouteHandler(releasesPath, r, releasesHandler(coinData), http.MethodGet)
+
+	// pin endpoint :: holds upgrades to a version, or to the newest release on a channel
+	pinPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "pin")
+	api.ProtectedRouteHandler(pinPath, r, getPinHandler(coinData), http.MethodGet)
+	api.ProtectedRouteHandler(pinPath, r, setPinHandler(coinData), http.MethodPost)
+
+}
+
+// releasesHandler returns the coin's published daemon releases
+func releasesHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		releases, err := daemon.GetReleases(coinData)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.ServerError
+			returnErr.ErrorMessage = "Failed to list " + coinData.CurrencyCode + " releases: " + err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = releases
+
+		apiResp.Send(w)
+
+	})
+}
+
+// getPinHandler returns the coin's pinned version and channel
+func getPinHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		apiResp.Data = daemon.GetPin(coinData)
+
+		apiResp.Send(w)
+
+	})
+}
+
+// setPinHandler pins the coin's version or channel and saves it to the app config,
+// starting the upgrade when the pinned version isn't the running one
+func setPinHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		incoming := daemon.Pin{}
+
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+			returnErr := api.AppRespErrors.JSONDecodeError
+			returnErr.ErrorMessage = "JSON decode error: " + err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		pin, err := daemon.SetPin(coinData, incoming)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = pin
+
+		apiResp.Send(w)
+
+	})
+}
@@ -0,0 +1,343 @@
+package daemonapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/address"
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/gorilla/mux"
+)
+
+// defaultRewardsPeriod is the period reported when no ?from= is supplied
+const defaultRewardsPeriod = 30 * 24 * time.Hour
+
+// medianTimeSpan is the number of previous blocks whose median time a block's
+// time must exceed, so block times are only in order across that many blocks
+const medianTimeSpan = 11
+
+// InitStakingHandlers sets up handlers for the node's staking status and address staking rewards
+func InitStakingHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "staking"
+
+	// info endpoint :: provides the node wallet's staking status
+	infoPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "info")
+	api.ProtectedRouteHandler(infoPath, r, stakingInfoHandler(coinData), http.MethodGet)
+
+	// rewards endpoint :: provides the staking rewards of an ?address= between ?from= and ?to=
+	rewardsPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "rewards")
+	api.OpenRouteHandler(rewardsPath, r, stakingRewardsHandler(coinData))
+
+}
+
+// StakingInfo is the decoded result of a 'getstakinginfo' RPC call
+// Weight and NetStakeWeight are in satoshis and ExpectedTime in seconds
+type StakingInfo struct {
+	Enabled          bool    `json:"enabled"`
+	Staking          bool    `json:"staking"`
+	Errors           string  `json:"errors"`
+	CurrentBlockSize int64   `json:"currentblocksize"`
+	CurrentBlockTx   int64   `json:"currentblocktx"`
+	PooledTx         int64   `json:"pooledtx"`
+	Difficulty       float64 `json:"difficulty"`
+	SearchInterval   int64   `json:"search-interval"`
+	Weight           int64   `json:"weight"`
+	NetStakeWeight   int64   `json:"netstakeweight"`
+	ExpectedTime     int64   `json:"expectedtime"`
+}
+
+// StakingReward is a coinstake transaction that rewarded an address
+type StakingReward struct {
+	TxID        string `json:"txid"`
+	BlockHeight int64  `json:"blockheight"`
+	Time        int64  `json:"time"`
+	Amount      int64  `json:"amount"`
+}
+
+// blockHeader is the part of a 'getblockheader' result the staking report needs
+type blockHeader struct {
+	Time int64 `json:"time"`
+}
+
+// StakingReport is an address's staking rewards between two unix times
+type StakingReport struct {
+	Address string          `json:"address"`
+	From    int64           `json:"from"`
+	To      int64           `json:"to"`
+	Count   int             `json:"count"`
+	Total   int64           `json:"total"`
+	Rewards []StakingReward `json:"rewards"`
+}
+
+// stakingInfoHandler returns the node wallet's staking status
+func stakingInfoHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		info, err := getStakingInfo(coinData)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Staking info error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = info
+
+		apiResp.Send(w)
+
+	})
+}
+
+// stakingRewardsHandler returns the staking report for the ?address=
+// over the unix times ?from= and ?to=, the last 30 days by default
+func stakingRewardsHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		addressStr := r.URL.Query().Get("address")
+
+		if address.IsSupported(coinData.CurrencyCode) {
+			if err := address.Validate(coinData.CurrencyCode, addressStr, coinData.UseTestNet); err != nil {
+				returnErr := api.AppRespErrors.InvalidAddress
+				returnErr.ErrorMessage = err.Error()
+				apiResp.Errors = append(apiResp.Errors, returnErr)
+				apiResp.Send(w)
+				return
+			}
+		}
+
+		from, to, err := parsePeriod(r.URL.Query().Get("from"), r.URL.Query().Get("to"), time.Now())
+
+		if err != nil {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		report, err := getStakingReport(coinData, addressStr, from, to)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Staking rewards error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = report
+
+		apiResp.Send(w)
+
+	})
+}
+
+// parsePeriod parses the unix times bounding a report, to defaults to now
+// and from defaults to the default rewards period before to
+func parsePeriod(fromStr string, toStr string, now time.Time) (int64, int64, error) {
+
+	to := now.Unix()
+
+	if toStr != "" {
+		parsed, err := strconv.ParseInt(toStr, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("to must be a unix time")
+		}
+		to = parsed
+	}
+
+	from := to - int64(defaultRewardsPeriod/time.Second)
+
+	if fromStr != "" {
+		parsed, err := strconv.ParseInt(fromStr, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("from must be a unix time")
+		}
+		from = parsed
+	}
+
+	if from > to {
+		return 0, 0, fmt.Errorf("from must be before to")
+	}
+
+	return from, to, nil
+
+}
+
+// getStakingInfo returns the decoded 'getstakinginfo' result
+func getStakingInfo(coinData conf.CoinData) (StakingInfo, error) {
+
+	info := StakingInfo{}
+
+	err := daemonrpc.CallDaemon(coinData, "getstakinginfo", nil, &info)
+
+	return info, err
+
+}
+
+// getStakingReport finds the coinstake transactions in the address's
+// confirmed history that were included in blocks between from and to
+// only the address's txids in the blocks that can fall in the period are
+// fetched, and only the coinstakes among them have their inputs resolved
+func getStakingReport(coinData conf.CoinData, addressStr string, from int64, to int64) (StakingReport, error) {
+
+	report := StakingReport{Address: addressStr, From: from, To: to, Rewards: []StakingReward{}}
+
+	tip := newChainTip(coinData)
+
+	start, end, err := periodHeights(coinData, from, to, tip)
+
+	if err != nil {
+		return StakingReport{}, err
+	}
+
+	if start > end {
+		return report, nil
+	}
+
+	txIDs, err := getConfirmedTxIDs(coinData, addressStr, start, end)
+
+	if err != nil {
+		return StakingReport{}, err
+	}
+
+	for _, txID := range txIDs {
+
+		tx, cached, err := getCachedTransaction(coinData, txID, addressStr, tip)
+
+		if err != nil {
+			return StakingReport{}, err
+		}
+
+		if !cached {
+
+			verboseTx, err := getVerboseTx(coinData, txID)
+
+			if err != nil {
+				return StakingReport{}, err
+			}
+
+			if !verboseTx.IsCoinStake() || verboseTx.BlockTime < from || verboseTx.BlockTime > to {
+				continue
+			}
+
+			if tx, err = completeTransaction(coinData, verboseTx, addressStr); err != nil {
+				return StakingReport{}, err
+			}
+
+		}
+
+		if !tx.CoinStake || tx.BlockTime < from || tx.BlockTime > to {
+			continue
+		}
+
+		report.Rewards = append(report.Rewards, StakingReward{
+			TxID:        tx.TxID,
+			BlockHeight: tx.BlockHeight,
+			Time:        tx.BlockTime,
+			Amount:      tx.Amount,
+		})
+
+		report.Total += tx.Amount
+
+	}
+
+	report.Count = len(report.Rewards)
+
+	return report, nil
+
+}
+
+// periodHeights returns the range of block heights that can hold blocks
+// timed between from and to, start is past end when there are none
+// block times only have to pass the median of the previous blocks so
+// the range found by searching them is widened by medianTimeSpan
+func periodHeights(coinData conf.CoinData, from int64, to int64, tip *chainTip) (int64, int64, error) {
+
+	height, err := tip.Height()
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start, err := firstBlockFrom(coinData, from, height)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := firstBlockFrom(coinData, to+1, height)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start -= medianTimeSpan
+	if start < 1 {
+		start = 1
+	}
+
+	end += medianTimeSpan - 1
+	if end > height {
+		end = height
+	}
+
+	return start, end, nil
+
+}
+
+// firstBlockFrom binary searches the chain up to height for the first
+// block timed at or after t, height+1 when every block is older
+func firstBlockFrom(coinData conf.CoinData, t int64, height int64) (int64, error) {
+
+	low, high := int64(1), height+1
+
+	for low < high {
+
+		mid := (low + high) / 2
+
+		blockTime, err := getBlockTime(coinData, mid)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if blockTime < t {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+
+	}
+
+	return low, nil
+
+}
+
+// getBlockTime returns the time of the block at height in the active chain
+func getBlockTime(coinData conf.CoinData, height int64) (int64, error) {
+
+	hash, err := getBlockHash(coinData, height)
+
+	if err != nil {
+		return 0, err
+	}
+
+	header := blockHeader{}
+
+	err = daemonrpc.CallDaemon(coinData, "getblockheader", []interface{}{hash}, &header)
+
+	return header.Time, err
+
+}
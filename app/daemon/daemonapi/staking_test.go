@@ -0,0 +1,192 @@
+package daemonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+// mock data struct for get staking info response
+func mockGetStakingInfoResponseData() string {
+	return `{"result": {
+  "enabled": true,
+  "staking": true,
+  "errors": "",
+  "currentblocksize": 1000,
+  "currentblocktx": 0,
+  "pooledtx": 2,
+  "difficulty": 12345.6,
+  "search-interval": 16,
+  "weight": 100000000000,
+  "netstakeweight": 3200000000000000,
+  "expectedtime": 86400
+}, "error": null, "id": null}`
+}
+
+// test that the staking info is decoded
+func Test_getStakingInfo(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{"getstakinginfo": mockGetStakingInfoResponseData()}))
+
+	info, err := getStakingInfo(rpctest.CoinData())
+
+	assert.Nil(t, err)
+	assert.True(t, info.Staking)
+	assert.Equal(t, int64(16), info.SearchInterval)
+	assert.Equal(t, int64(100000000000), info.Weight)
+	assert.Equal(t, int64(86400), info.ExpectedTime)
+
+}
+
+// test that coinstakes are told apart by their empty first output
+func Test_IsCoinStake(t *testing.T) {
+
+	coinStake := VerboseTx{
+		Vin:  []VerboseVin{{TxID: "aa", Vout: 1}},
+		Vout: []VerboseVout{{N: 0}, {N: 1, ValueSat: 500}},
+	}
+
+	assert.True(t, coinStake.IsCoinStake())
+
+	payment := VerboseTx{
+		Vin:  []VerboseVin{{TxID: "aa", Vout: 1}},
+		Vout: []VerboseVout{{N: 0, ValueSat: 500, ScriptPubKey: ScriptPubKey{Hex: "76a9"}}, {N: 1, ValueSat: 500}},
+	}
+
+	assert.False(t, payment.IsCoinStake())
+
+	coinbase := VerboseTx{
+		Vin:  []VerboseVin{{Coinbase: "03"}},
+		Vout: []VerboseVout{{N: 0}, {N: 1, ValueSat: 500}},
+	}
+
+	assert.False(t, coinbase.IsCoinStake())
+
+}
+
+// test the default and invalid report periods
+func Test_parsePeriod(t *testing.T) {
+
+	now := time.Unix(1500000000, 0)
+
+	from, to, err := parsePeriod("", "", now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1500000000), to)
+	assert.Equal(t, int64(1500000000-30*24*60*60), from)
+
+	from, to, err = parsePeriod("100", "200", now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), from)
+	assert.Equal(t, int64(200), to)
+
+	_, _, err = parsePeriod("200", "100", now)
+	assert.NotNil(t, err)
+
+	_, _, err = parsePeriod("yesterday", "", now)
+	assert.NotNil(t, err)
+
+}
+
+// mockBlocksResponder answers 'getblockhash' and 'getblockheader' for a chain
+// with a block a minute, the mocked coinstakes' block 523 being at their
+// blocktime, records the 'getaddresstxids' params and mocks the other methods
+func mockBlocksResponder(responses map[string]string, txIDParams *[]GetTxIDParams) httpmock.Responder {
+
+	others := rpctest.Responder(responses)
+
+	return func(req *http.Request) (*http.Response, error) {
+
+		body, _ := ioutil.ReadAll(req.Body)
+
+		rpcReq := struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		json.Unmarshal(body, &rpcReq)
+
+		switch rpcReq.Method {
+		case "getblockhash":
+			return httpmock.NewStringResponse(200, fmt.Sprintf(`{"result": "block%s"}`, rpcReq.Params[0])), nil
+		case "getblockheader":
+			hash := ""
+			json.Unmarshal(rpcReq.Params[0], &hash)
+			height, _ := strconv.ParseInt(strings.TrimPrefix(hash, "block"), 10, 64)
+			return httpmock.NewStringResponse(200, fmt.Sprintf(`{"result": {"time": %d}}`, 1463088112+(height-523)*60)), nil
+		case "getaddresstxids":
+			params := GetTxIDParams{}
+			json.Unmarshal(rpcReq.Params[0], &params)
+			*txIDParams = append(*txIDParams, params)
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		return others(req)
+
+	}
+
+}
+
+// test that the rewards of coinstakes in the period are totalled and
+// only the address's txids in the period's blocks are looked up
+func Test_getStakingReport(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	txIDParams := []GetTxIDParams{}
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		mockBlocksResponder(map[string]string{
+			"getaddresstxids":   `{"result": ["c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929", "52489abff43212445d432f6042e5b9faf99b3c843a79210629b5383f52694ec5"]}`,
+			"getrawtransaction": mockGetRawTxVerboseResponseData(),
+			"getblockcount":     mockGetBlockCountResponseData(56291),
+		}, &txIDParams))
+
+	report, err := getStakingReport(rpctest.CoinData(), "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", 1463000000, 1463100000)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Count)
+	assert.Equal(t, int64(1463088112), report.Rewards[0].Time)
+	assert.Equal(t, int64(500011449616438), report.Rewards[0].Amount)
+	assert.Equal(t, int64(2*500011449616438), report.Total)
+
+	// the period ends with block 721, widened by the median time span
+	assert.Equal(t, int64(1), txIDParams[0].Start)
+	assert.Equal(t, int64(732), txIDParams[0].End)
+
+	// the coinstakes are outside the period
+	report, err = getStakingReport(rpctest.CoinData(), "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", 1463100000, 1463200000)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, report.Count)
+	assert.Equal(t, 0, len(report.Rewards))
+
+	// the period runs from block 722 to 2387
+	assert.Equal(t, int64(711), txIDParams[1].Start)
+	assert.Equal(t, int64(2398), txIDParams[1].End)
+
+	// a period after the tip only looks at the blocks whose times may be out of order
+	report, err = getStakingReport(rpctest.CoinData(), "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", 1600000000, 1600100000)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, report.Count)
+	assert.Equal(t, int64(56281), txIDParams[2].Start)
+	assert.Equal(t, int64(56291), txIDParams[2].End)
+
+}
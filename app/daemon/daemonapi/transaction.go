@@ -0,0 +1,442 @@
+package daemonapi
+
+import (
+	"github.com/Encrypt-S/kauri-api/app/address"
+	"github.com/Encrypt-S/kauri-api/app/cache"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+)
+
+// Directions of a transaction relative to the requested address
+const (
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+	DirectionSelf     = "self"
+)
+
+// OutputTypeColdStaking is the type of an output delegated to a cold staking address
+const OutputTypeColdStaking = "cold_staking"
+
+// satoshisPerCoin converts daemon coin values to integer satoshis
+const satoshisPerCoin = 100000000
+
+// Transaction cache defaults used when the server config doesn't set them
+const (
+	defaultTxCacheSize             = 10000
+	defaultTxCacheMinConfirmations = 6
+)
+
+// Transaction is the normalised transaction returned to clients
+// all amounts are in integer satoshis and Amount is the net change
+// for the address the transaction was requested for
+type Transaction struct {
+	TxID          string     `json:"txid"`
+	RawTx         string     `json:"rawtx,omitempty"`
+	BlockHash     string     `json:"blockhash,omitempty"`
+	BlockHeight   int64      `json:"blockheight"`
+	BlockTime     int64      `json:"blocktime,omitempty"`
+	Confirmations int64      `json:"confirmations"`
+	Time          int64      `json:"time"`
+	Inputs        []TxInput  `json:"inputs"`
+	Outputs       []TxOutput `json:"outputs"`
+	Fee           int64      `json:"fee"`
+	Direction     string     `json:"direction"`
+	Amount        int64      `json:"amount"`
+	Unconfirmed   bool       `json:"unconfirmed"`
+	CoinStake     bool       `json:"coinstake,omitempty"`
+}
+
+// TxInput is a transaction input with its resolved prevout address and value
+// inputs spending cold staking outputs also have the staking and spending addresses
+type TxInput struct {
+	TxID            string `json:"txid,omitempty"`
+	Vout            int    `json:"vout"`
+	Coinbase        bool   `json:"coinbase,omitempty"`
+	Address         string `json:"address,omitempty"`
+	StakingAddress  string `json:"stakingaddress,omitempty"`
+	SpendingAddress string `json:"spendingaddress,omitempty"`
+	Value           int64  `json:"value"`
+}
+
+// TxOutput is a transaction output
+// cold staking outputs are paid to the cold staking address, and
+// also have the staking address and the spending address that owns them
+type TxOutput struct {
+	N               int    `json:"n"`
+	Address         string `json:"address,omitempty"`
+	StakingAddress  string `json:"stakingaddress,omitempty"`
+	SpendingAddress string `json:"spendingaddress,omitempty"`
+	Value           int64  `json:"value"`
+	Type            string `json:"type"`
+}
+
+// VerboseTx is the decoded result of a verbose 'getrawtransaction' RPC call
+type VerboseTx struct {
+	TxID          string        `json:"txid"`
+	Hash          string        `json:"hash"`
+	Hex           string        `json:"hex"`
+	Size          int           `json:"size"`
+	Version       int           `json:"version"`
+	LockTime      int64         `json:"locktime"`
+	Time          int64         `json:"time"`
+	BlockHash     string        `json:"blockhash"`
+	BlockTime     int64         `json:"blocktime"`
+	Height        int64         `json:"height"`
+	Confirmations int64         `json:"confirmations"`
+	Vin           []VerboseVin  `json:"vin"`
+	Vout          []VerboseVout `json:"vout"`
+}
+
+// VerboseVin is a verbose transaction input
+type VerboseVin struct {
+	TxID     string `json:"txid"`
+	Vout     int    `json:"vout"`
+	Coinbase string `json:"coinbase"`
+	Sequence uint32 `json:"sequence"`
+}
+
+// VerboseVout is a verbose transaction output
+type VerboseVout struct {
+	Value        float64      `json:"value"`
+	ValueSat     int64        `json:"valueSat"`
+	N            int          `json:"n"`
+	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
+}
+
+// ScriptPubKey is the decoded output script of a verbose transaction output
+type ScriptPubKey struct {
+	Asm       string   `json:"asm"`
+	Hex       string   `json:"hex"`
+	ReqSigs   int      `json:"reqSigs"`
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses"`
+}
+
+// txCache holds confirmed transactions with their resolved inputs keyed by
+// currency and txid - a transaction never changes once it is buried deep
+// enough, only its confirmations which are worked out from the chain height
+var txCache = cache.New("transactions", defaultTxCacheSize)
+
+// outputsCache holds the outputs of confirmed previous transactions looked
+// up to resolve inputs, keyed by currency and txid
+var outputsCache = cache.New("outputs", defaultTxCacheSize)
+
+// txCacheMinConfirmations is the confirmations a transaction needs to be cached
+var txCacheMinConfirmations int64 = defaultTxCacheMinConfirmations
+
+// cachedTx is a transaction cache entry
+type cachedTx struct {
+	Tx     VerboseTx `json:"tx"`
+	Inputs []TxInput `json:"inputs"`
+}
+
+// InitTxCaches sizes the transaction caches from the server config and
+// returns them so the caller can persist them if configured to
+func InitTxCaches(serverConf conf.ServerConfig) []*cache.Cache {
+
+	if serverConf.TxCacheSize > 0 {
+		txCache.Resize(serverConf.TxCacheSize)
+		outputsCache.Resize(serverConf.TxCacheSize)
+	}
+
+	if serverConf.TxCacheMinConfirmations > 0 {
+		txCacheMinConfirmations = serverConf.TxCacheMinConfirmations
+	}
+
+	return []*cache.Cache{txCache, outputsCache}
+
+}
+
+// IsCoinStake reports whether the transaction is a proof-of-stake reward
+// a coinstake spends at least one input and marks itself with an empty first output
+func (verboseTx VerboseTx) IsCoinStake() bool {
+
+	if len(verboseTx.Vin) == 0 || verboseTx.Vin[0].Coinbase != "" || len(verboseTx.Vout) < 2 {
+		return false
+	}
+
+	first := verboseTx.Vout[0]
+
+	return first.Satoshis() == 0 && first.ScriptPubKey.Hex == ""
+
+}
+
+// Satoshis returns the output value in integer satoshis
+func (vout VerboseVout) Satoshis() int64 {
+	if vout.ValueSat != 0 || vout.Value == 0 {
+		return vout.ValueSat
+	}
+	return int64(vout.Value*satoshisPerCoin + 0.5)
+}
+
+// Output classifies the output, recognising cold staking scripts by their hex
+func (vout VerboseVout) Output(coinData conf.CoinData) TxOutput {
+
+	output := TxOutput{N: vout.N, Address: vout.Address(), Value: vout.Satoshis(), Type: vout.ScriptPubKey.Type}
+
+	stakingHash, spendingHash, ok := address.ParseColdStakingScript(vout.ScriptPubKey.Hex)
+
+	if !ok || !address.IsSupported(coinData.CurrencyCode) {
+		return output
+	}
+
+	output.Type = OutputTypeColdStaking
+	output.Address, _ = address.EncodeColdStaking(coinData.CurrencyCode, stakingHash, spendingHash, coinData.UseTestNet)
+	output.StakingAddress, _ = address.EncodePubKeyHash(coinData.CurrencyCode, stakingHash, coinData.UseTestNet)
+	output.SpendingAddress, _ = address.EncodePubKeyHash(coinData.CurrencyCode, spendingHash, coinData.UseTestNet)
+
+	return output
+
+}
+
+// Address returns the first address paid by the output, if any
+func (vout VerboseVout) Address() string {
+	if len(vout.ScriptPubKey.Addresses) == 0 {
+		return ""
+	}
+	return vout.ScriptPubKey.Addresses[0]
+}
+
+// chainTip is the chain height for a request's cached transactions,
+// it is only requested for the first cache hit so a request costs one call
+type chainTip struct {
+	coinData conf.CoinData
+	height   int64
+	fetched  bool
+}
+
+// newChainTip returns the coin's chain tip for one request
+func newChainTip(coinData conf.CoinData) *chainTip {
+	return &chainTip{coinData: coinData}
+}
+
+// Height returns the chain height, requesting it the first time
+func (tip *chainTip) Height() (int64, error) {
+
+	if tip.fetched {
+		return tip.height, nil
+	}
+
+	height, err := getBlockCount(tip.coinData)
+
+	if err != nil {
+		return 0, err
+	}
+
+	tip.height, tip.fetched = height, true
+
+	return height, nil
+
+}
+
+// getTransaction fetches the verbose transaction for txid, resolves its inputs
+// and returns the normalised transaction from the point of view of address
+// confirmed transactions come from the cache where possible, their
+// confirmations are counted from the request's chain tip
+func getTransaction(coinData conf.CoinData, txid string, address string, tip *chainTip) (Transaction, error) {
+
+	if tx, cached, err := getCachedTransaction(coinData, txid, address, tip); cached || err != nil {
+		return tx, err
+	}
+
+	verboseTx, err := getVerboseTx(coinData, txid)
+
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	return completeTransaction(coinData, verboseTx, address)
+
+}
+
+// getCachedTransaction returns the cached transaction for txid, if there is one,
+// with its confirmations counted from the request's chain tip
+func getCachedTransaction(coinData conf.CoinData, txid string, address string, tip *chainTip) (Transaction, bool, error) {
+
+	cached := cachedTx{}
+
+	if !txCache.Get(coinData.CurrencyCode+":"+txid, &cached) {
+		return Transaction{}, false, nil
+	}
+
+	height, err := tip.Height()
+
+	if err != nil {
+		return Transaction{}, false, err
+	}
+
+	cached.Tx.Confirmations = height - cached.Tx.Height + 1
+
+	return buildTransaction(coinData, cached.Tx, cached.Inputs, address), true, nil
+
+}
+
+// completeTransaction resolves the inputs of the fetched verbose transaction,
+// caching it once it is confirmed deeply enough, and normalises it for address
+func completeTransaction(coinData conf.CoinData, verboseTx VerboseTx, address string) (Transaction, error) {
+
+	inputs, err := resolveInputs(coinData, verboseTx)
+
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	if verboseTx.Confirmations >= txCacheMinConfirmations {
+		txCache.Set(coinData.CurrencyCode+":"+verboseTx.TxID, cachedTx{Tx: verboseTx, Inputs: inputs})
+	}
+
+	return buildTransaction(coinData, verboseTx, inputs, address), nil
+
+}
+
+// buildTransaction normalises the verbose transaction and its resolved inputs
+// coins in cold staking outputs count towards the spending address that owns them
+func buildTransaction(coinData conf.CoinData, verboseTx VerboseTx, inputs []TxInput, address string) Transaction {
+
+	tx := Transaction{
+		TxID:          verboseTx.TxID,
+		RawTx:         verboseTx.Hex,
+		BlockHash:     verboseTx.BlockHash,
+		BlockHeight:   verboseTx.Height,
+		BlockTime:     verboseTx.BlockTime,
+		Confirmations: verboseTx.Confirmations,
+		Time:          verboseTx.Time,
+		Inputs:        inputs,
+		Unconfirmed:   verboseTx.Confirmations == 0,
+		CoinStake:     verboseTx.IsCoinStake(),
+	}
+
+	var totalIn, totalOut int64
+	isCoinbase := false
+
+	for _, input := range inputs {
+		totalIn += input.Value
+		isCoinbase = isCoinbase || input.Coinbase
+		if input.Address == address || input.SpendingAddress == address {
+			tx.Amount -= input.Value
+		}
+	}
+
+	for _, vout := range verboseTx.Vout {
+		output := vout.Output(coinData)
+		tx.Outputs = append(tx.Outputs, output)
+		totalOut += output.Value
+		if output.Address == address || output.SpendingAddress == address {
+			tx.Amount += output.Value
+		}
+	}
+
+	// coinbase and coinstake transactions create coins so they pay no fee
+	if !isCoinbase && totalIn > totalOut {
+		tx.Fee = totalIn - totalOut
+	}
+
+	// without an address there is no direction to report
+	if address == "" {
+		return tx
+	}
+
+	switch {
+	case tx.Amount > 0:
+		tx.Direction = DirectionIncoming
+	case tx.Amount < 0:
+		tx.Direction = DirectionOutgoing
+	default:
+		tx.Direction = DirectionSelf
+	}
+
+	return tx
+
+}
+
+// resolveInputs looks up the previous outputs spent by the transaction
+// to find the address and value of each of its inputs
+func resolveInputs(coinData conf.CoinData, verboseTx VerboseTx) ([]TxInput, error) {
+
+	inputs := []TxInput{}
+
+	for _, vin := range verboseTx.Vin {
+
+		if vin.Coinbase != "" {
+			inputs = append(inputs, TxInput{Coinbase: true})
+			continue
+		}
+
+		prevOutputs, err := getPrevOutputs(coinData, vin.TxID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		input := TxInput{TxID: vin.TxID, Vout: vin.Vout}
+
+		for _, prevOut := range prevOutputs {
+			if prevOut.N == vin.Vout {
+				output := prevOut.Output(coinData)
+				input.Address = output.Address
+				input.StakingAddress = output.StakingAddress
+				input.SpendingAddress = output.SpendingAddress
+				input.Value = output.Value
+			}
+		}
+
+		inputs = append(inputs, input)
+
+	}
+
+	return inputs, nil
+
+}
+
+// getPrevOutputs returns the outputs of a previous transaction, from the cache if possible
+func getPrevOutputs(coinData conf.CoinData, txid string) ([]VerboseVout, error) {
+
+	key := coinData.CurrencyCode + ":" + txid
+	cached := cachedTx{}
+
+	if txCache.Get(key, &cached) {
+		return cached.Tx.Vout, nil
+	}
+
+	outputs := []VerboseVout{}
+
+	if outputsCache.Get(key, &outputs) {
+		return outputs, nil
+	}
+
+	prevTx, err := getVerboseTx(coinData, txid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if prevTx.Confirmations >= txCacheMinConfirmations {
+		outputsCache.Set(key, prevTx.Vout)
+	}
+
+	return prevTx.Vout, nil
+
+}
+
+// getBlockCount returns the height of the coin's chain tip
+func getBlockCount(coinData conf.CoinData) (int64, error) {
+
+	var height int64
+
+	err := daemonrpc.CallDaemon(coinData, "getblockcount", nil, &height)
+
+	return height, err
+
+}
+
+// getVerboseTx takes txid and returns the decoded verbose transaction data
+func getVerboseTx(coinData conf.CoinData, txid string) (VerboseTx, error) {
+
+	verboseTx := VerboseTx{}
+
+	// called through CallDaemon so an RPC error isn't read as an empty transaction
+	err := daemonrpc.CallDaemon(coinData, "getrawtransaction", []interface{}{txid, 1}, &verboseTx)
+
+	return verboseTx, err
+
+}
@@ -0,0 +1,99 @@
+package daemonapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon"
+	"github.com/gorilla/mux"
+)
+
+// IncomingUpgrade is the optional body of an upgrade request, HealthTimeout is in seconds
+type IncomingUpgrade struct {
+	HealthTimeout int64 `json:"healthTimeout"`
+}
+
+// InitUpgradeHandlers sets up the protected handlers to upgrade the coin's daemon and follow its progress
+func InitUpgradeHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "daemon"
+
+	// upgrade endpoint :: starts an upgrade to the latest release, or reports the last one
+	upgradePath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "upgrade")
+	api.ProtectedRouteHandler(upgradePath, r, startUpgradeHandler(coinData), http.MethodPost)
+	api.ProtectedRouteHandler(upgradePath, r, upgradeStatusHandler(coinData), http.MethodGet)
+
+}
+
+// startUpgradeHandler starts the daemon's upgrade and returns its initial status
+func startUpgradeHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		incoming := IncomingUpgrade{}
+
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil && err != io.EOF {
+			returnErr := api.AppRespErrors.JSONDecodeError
+			returnErr.ErrorMessage = "JSON decode error: " + err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if incoming.HealthTimeout < 0 {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = "healthTimeout must not be negative"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		healthTimeout := daemon.DefaultHealthTimeout
+		if incoming.HealthTimeout > 0 {
+			healthTimeout = time.Duration(incoming.HealthTimeout) * time.Second
+		}
+
+		status, err := daemon.StartUpgrade(coinData, healthTimeout)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = status
+
+		apiResp.Send(w)
+
+	})
+}
+
+// upgradeStatusHandler returns the status of the daemon's last upgrade
+func upgradeStatusHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		status, ok := daemon.GetUpgradeStatus(coinData.CurrencyCode)
+
+		if !ok {
+			returnErr := api.AppRespErrors.NotFound
+			returnErr.ErrorMessage = "No upgrade has run for " + coinData.CurrencyCode
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = status
+
+		apiResp.Send(w)
+
+	})
+}
@@ -0,0 +1,209 @@
+package daemonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/gorilla/mux"
+)
+
+// The node's stance on a proposal or payment request, VoteRemove clears it
+const (
+	VoteYes    = "yes"
+	VoteNo     = "no"
+	VoteNone   = "none"
+	VoteRemove = "remove"
+)
+
+// IncomingVote is the body of a vote request
+type IncomingVote struct {
+	Vote string `json:"vote"`
+}
+
+// VoteResult is the node's vote after a vote request
+// votes are only counted in blocks the node stakes, CanVote is whether it is staking
+type VoteResult struct {
+	Hash    string `json:"hash"`
+	Vote    string `json:"vote"`
+	CanVote bool   `json:"canVote"`
+}
+
+// NodeVotes is the proposals with the node's vote on each of them and their payment requests
+type NodeVotes struct {
+	CanVote   bool       `json:"canVote"`
+	Proposals []Proposal `json:"proposals"`
+}
+
+// voteList is the decoded result of a 'proposalvotelist' or 'paymentrequestvotelist' RPC call
+type voteList struct {
+	Yes []struct {
+		Hash string `json:"hash"`
+	} `json:"yes"`
+	No []struct {
+		Hash string `json:"hash"`
+	} `json:"no"`
+}
+
+// votes maps the hashes in the vote list to the node's vote
+func (list voteList) votes() map[string]string {
+
+	votes := map[string]string{}
+
+	for _, entry := range list.Yes {
+		votes[entry.Hash] = VoteYes
+	}
+
+	for _, entry := range list.No {
+		votes[entry.Hash] = VoteNo
+	}
+
+	return votes
+
+}
+
+// nodeVotesHandler returns all the proposals with the node's votes
+func nodeVotesHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		nodeVotes, err := getNodeVotes(coinData)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Votes error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = nodeVotes
+
+		apiResp.Send(w)
+
+	})
+}
+
+// voteHandler sets the node's vote on the hash in the route with the RPC method
+func voteHandler(coinData conf.CoinData, method string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		incoming := IncomingVote{}
+
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+			returnErr := api.AppRespErrors.JSONDecodeError
+			returnErr.ErrorMessage = fmt.Sprintf("JSON decode error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if incoming.Vote != VoteYes && incoming.Vote != VoteNo && incoming.Vote != VoteRemove {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = "vote must be one of yes, no or remove"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		result, err := castVote(coinData, method, mux.Vars(r)["hash"], incoming.Vote)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Vote error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = result
+
+		apiResp.Send(w)
+
+	})
+}
+
+// castVote sets the node's vote on the hash and reports whether it can currently be cast
+func castVote(coinData conf.CoinData, method string, hash string, vote string) (VoteResult, error) {
+
+	// the daemon answers with a message rather than a result
+	var message interface{}
+
+	if err := daemonrpc.CallDaemon(coinData, method, []interface{}{hash, vote}, &message); err != nil {
+		return VoteResult{}, err
+	}
+
+	info, err := getStakingInfo(coinData)
+
+	if err != nil {
+		return VoteResult{}, err
+	}
+
+	if vote == VoteRemove {
+		vote = VoteNone
+	}
+
+	return VoteResult{Hash: hash, Vote: vote, CanVote: info.Staking}, nil
+
+}
+
+// getNodeVotes lists all proposals and their payment requests with the node's vote on each
+func getNodeVotes(coinData conf.CoinData) (NodeVotes, error) {
+
+	proposals, err := getProposals(coinData, "")
+
+	if err != nil {
+		return NodeVotes{}, err
+	}
+
+	proposalVotes := voteList{}
+
+	if err := daemonrpc.CallDaemon(coinData, "proposalvotelist", nil, &proposalVotes); err != nil {
+		return NodeVotes{}, err
+	}
+
+	paymentRequestVotes := voteList{}
+
+	if err := daemonrpc.CallDaemon(coinData, "paymentrequestvotelist", nil, &paymentRequestVotes); err != nil {
+		return NodeVotes{}, err
+	}
+
+	info, err := getStakingInfo(coinData)
+
+	if err != nil {
+		return NodeVotes{}, err
+	}
+
+	votes := proposalVotes.votes()
+	requestVotes := paymentRequestVotes.votes()
+
+	for i := range proposals {
+
+		proposals[i].Vote = stance(votes, proposals[i].Hash)
+
+		for j := range proposals[i].PaymentRequests {
+			proposals[i].PaymentRequests[j].Vote = stance(requestVotes, proposals[i].PaymentRequests[j].Hash)
+		}
+
+	}
+
+	return NodeVotes{CanVote: info.Staking, Proposals: proposals}, nil
+
+}
+
+// stance returns the node's vote on the hash, VoteNone if it hasn't voted
+func stance(votes map[string]string, hash string) string {
+
+	if vote, ok := votes[hash]; ok {
+		return vote
+	}
+
+	return VoteNone
+
+}
@@ -0,0 +1,72 @@
+package daemonapi
+
+import (
+	"testing"
+
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+// test that the node's votes are set on the proposals and their payment requests
+func Test_getNodeVotes(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	daoCache.Purge()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"getbestblockhash":       mockGetBlockHashResponseData(),
+			"listproposals":          mockListProposalsResponseData(),
+			"getblock":               mockGetBlockResponseData(),
+			"proposalvotelist":       `{"result": {"yes": [], "no": [{"hash": "8f1a6a32ed1b2cf3b8b0ba9e3c4b5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e"}], "null": []}, "error": null, "id": null}`,
+			"paymentrequestvotelist": `{"result": {"yes": [], "no": [], "null": [{"hash": "d2f9d4a5f0e9c4e10e1c1a09c6b1a1f4c2e3d5b6a7f8091a2b3c4d5e6f708192"}]}, "error": null, "id": null}`,
+			"getstakinginfo":         mockGetStakingInfoResponseData(),
+		}))
+
+	nodeVotes, err := getNodeVotes(rpctest.CoinData())
+
+	assert.Nil(t, err)
+	assert.True(t, nodeVotes.CanVote)
+	assert.Equal(t, 1, len(nodeVotes.Proposals))
+	assert.Equal(t, VoteNo, nodeVotes.Proposals[0].Vote)
+	assert.Equal(t, VoteNone, nodeVotes.Proposals[0].PaymentRequests[0].Vote)
+
+	// the votes aren't cached with the proposals
+	proposals, err := getProposals(rpctest.CoinData(), "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", proposals[0].Vote)
+
+}
+
+// test that a vote reports whether the node is staking
+func Test_castVote(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"proposalvote":   `{"result": null, "error": null, "id": null}`,
+			"getstakinginfo": `{"result": {"enabled": true, "staking": false}, "error": null, "id": null}`,
+		}))
+
+	result, err := castVote(rpctest.CoinData(), "proposalvote", "8f1a", VoteRemove)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "8f1a", result.Hash)
+	assert.Equal(t, VoteNone, result.Vote)
+	assert.False(t, result.CanVote)
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0",
+		rpctest.Responder(map[string]string{
+			"proposalvote": `{"result": null, "error": {"code": -5, "message": "Could not find proposal"}, "id": null}`,
+		}))
+
+	_, err = castVote(rpctest.CoinData(), "proposalvote", "8f1a", VoteYes)
+	assert.NotNil(t, err)
+
+}
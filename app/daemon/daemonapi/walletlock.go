@@ -0,0 +1,314 @@
+package daemonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/gorilla/mux"
+)
+
+// restartTimeout is how long the daemon is given to shut down after encrypting its wallet
+const restartTimeout = 2 * time.Minute
+
+// restartDaemon restarts the coin's daemon through the manager, replaced in tests
+var restartDaemon = daemon.Restart
+
+// Passphrase is a wallet passphrase, it is redacted when formatted so it can't be logged
+type Passphrase string
+
+// String redacts the passphrase
+func (Passphrase) String() string {
+	return "[redacted]"
+}
+
+// GoString redacts the passphrase
+func (Passphrase) GoString() string {
+	return "[redacted]"
+}
+
+// IncomingEncrypt is the body of an encrypt request
+type IncomingEncrypt struct {
+	Passphrase Passphrase `json:"passphrase"`
+}
+
+// IncomingUnlock is the body of an unlock request, Timeout is in seconds
+// a staking only unlock with no timeout stays unlocked until the wallet is locked
+type IncomingUnlock struct {
+	Passphrase  Passphrase `json:"passphrase"`
+	Timeout     int64      `json:"timeout"`
+	StakingOnly bool       `json:"stakingOnly"`
+}
+
+// IncomingPassphraseChange is the body of a passphrase change request
+type IncomingPassphraseChange struct {
+	OldPassphrase Passphrase `json:"oldPassphrase"`
+	NewPassphrase Passphrase `json:"newPassphrase"`
+}
+
+// walletInfo is the part of a 'getwalletinfo' result the lock status needs
+// UnlockedUntil is missing for an unencrypted wallet and 0 for a locked one
+type walletInfo struct {
+	UnlockedUntil *int64 `json:"unlocked_until"`
+}
+
+// WalletLockStatus is the wallet's state after a lock request
+type WalletLockStatus struct {
+	Encrypted   bool `json:"encrypted"`
+	Locked      bool `json:"locked"`
+	StakingOnly bool `json:"stakingOnly,omitempty"`
+	Restarting  bool `json:"restarting,omitempty"`
+}
+
+// InitWalletLockHandlers sets up handlers to encrypt, unlock and lock the node's wallet
+func InitWalletLockHandlers(r *mux.Router, coinData conf.CoinData, prefix string) {
+
+	namespace := "wallet"
+
+	// encrypt endpoint :: encrypts the wallet and restarts the daemon
+	encryptPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "encrypt")
+	api.ProtectedRouteHandler(encryptPath, r, encryptWalletHandler(coinData), http.MethodPost)
+
+	// unlock endpoint :: unlocks the wallet, optionally for staking only
+	unlockPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "unlock")
+	api.ProtectedRouteHandler(unlockPath, r, unlockWalletHandler(coinData), http.MethodPost)
+
+	// lock endpoint :: locks the wallet
+	lockPath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "lock")
+	api.ProtectedRouteHandler(lockPath, r, lockWalletHandler(coinData), http.MethodPost)
+
+	// passphrase endpoint :: changes the wallet's passphrase
+	passphrasePath := api.CoinRouteBuilder(prefix, namespace, "v1", coinData, "passphrase")
+	api.ProtectedRouteHandler(passphrasePath, r, changePassphraseHandler(coinData), http.MethodPost)
+
+}
+
+// encryptWalletHandler encrypts the wallet with the incoming passphrase
+func encryptWalletHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		incoming := IncomingEncrypt{}
+
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+			// the decode error is left out as it can quote the passphrase
+			returnErr := api.AppRespErrors.JSONDecodeError
+			returnErr.ErrorMessage = "JSON decode error: the body is not a valid request"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if incoming.Passphrase == "" {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = "passphrase is required"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		status, err := encryptWallet(coinData, incoming.Passphrase)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Encrypt wallet error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = status
+
+		apiResp.Send(w)
+
+	})
+}
+
+// unlockWalletHandler unlocks the wallet for the incoming timeout
+func unlockWalletHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		incoming := IncomingUnlock{}
+
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+			// the decode error is left out as it can quote the passphrase
+			returnErr := api.AppRespErrors.JSONDecodeError
+			returnErr.ErrorMessage = "JSON decode error: the body is not a valid request"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if err := validateUnlock(incoming); err != nil {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		status, err := unlockWallet(coinData, incoming)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Unlock wallet error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = status
+
+		apiResp.Send(w)
+
+	})
+}
+
+// lockWalletHandler locks the wallet
+func lockWalletHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		if err := daemonrpc.CallDaemon(coinData, "walletlock", nil, nil); err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Lock wallet error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = WalletLockStatus{Encrypted: true, Locked: true}
+
+		apiResp.Send(w)
+
+	})
+}
+
+// changePassphraseHandler changes the wallet's passphrase
+func changePassphraseHandler(coinData conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		incoming := IncomingPassphraseChange{}
+
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+			// the decode error is left out as it can quote the passphrase
+			returnErr := api.AppRespErrors.JSONDecodeError
+			returnErr.ErrorMessage = "JSON decode error: the body is not a valid request"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		if incoming.OldPassphrase == "" || incoming.NewPassphrase == "" {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = "oldPassphrase and newPassphrase are required"
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		status, err := changePassphrase(coinData, incoming)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Change passphrase error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = status
+
+		apiResp.Send(w)
+
+	})
+}
+
+// validateUnlock checks the unlock request has a passphrase and a usable timeout
+func validateUnlock(incoming IncomingUnlock) error {
+
+	if incoming.Passphrase == "" {
+		return fmt.Errorf("passphrase is required")
+	}
+
+	if incoming.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+
+	if incoming.Timeout == 0 && !incoming.StakingOnly {
+		return fmt.Errorf("timeout is required unless unlocking for staking only")
+	}
+
+	return nil
+
+}
+
+// encryptWallet encrypts the wallet, which shuts the daemon down,
+// and has the manager start it again once it has exited
+func encryptWallet(coinData conf.CoinData, passphrase Passphrase) (WalletLockStatus, error) {
+
+	// the daemon answers with a message rather than a result
+	var message interface{}
+
+	if err := daemonrpc.CallDaemon(coinData, "encryptwallet", []interface{}{string(passphrase)}, &message); err != nil {
+		return WalletLockStatus{}, err
+	}
+
+	go func() {
+		if err := restartDaemon(coinData, restartTimeout); err != nil {
+			log.Println("Failed to restart the " + coinData.CurrencyCode + " daemon after encrypting its wallet: " + err.Error())
+		}
+	}()
+
+	return WalletLockStatus{Encrypted: true, Locked: true, Restarting: true}, nil
+
+}
+
+// unlockWallet unlocks the wallet for the timeout, for staking only if requested
+func unlockWallet(coinData conf.CoinData, incoming IncomingUnlock) (WalletLockStatus, error) {
+
+	params := []interface{}{string(incoming.Passphrase), incoming.Timeout, incoming.StakingOnly}
+
+	if err := daemonrpc.CallDaemon(coinData, "walletpassphrase", params, nil); err != nil {
+		return WalletLockStatus{}, err
+	}
+
+	return WalletLockStatus{Encrypted: true, Locked: incoming.StakingOnly, StakingOnly: incoming.StakingOnly}, nil
+
+}
+
+// changePassphrase changes the wallet's passphrase, which leaves it locked or
+// unlocked as it was, and reads the lock state back from the daemon
+func changePassphrase(coinData conf.CoinData, incoming IncomingPassphraseChange) (WalletLockStatus, error) {
+
+	params := []interface{}{string(incoming.OldPassphrase), string(incoming.NewPassphrase)}
+
+	if err := daemonrpc.CallDaemon(coinData, "walletpassphrasechange", params, nil); err != nil {
+		return WalletLockStatus{}, err
+	}
+
+	info := walletInfo{}
+
+	if err := daemonrpc.CallDaemon(coinData, "getwalletinfo", nil, &info); err != nil {
+		return WalletLockStatus{}, err
+	}
+
+	if info.UnlockedUntil == nil {
+		return WalletLockStatus{}, nil
+	}
+
+	return WalletLockStatus{Encrypted: true, Locked: *info.UnlockedUntil == 0}, nil
+
+}
@@ -0,0 +1,166 @@
+package daemonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+// mockParamsResponder records the params of each rpc method called
+func mockParamsResponder(calls map[string][]interface{}) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		rpcReq := struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}{}
+		json.NewDecoder(req.Body).Decode(&rpcReq)
+		calls[rpcReq.Method] = rpcReq.Params
+		return httpmock.NewStringResponse(200, `{"result": null, "error": null, "id": null}`), nil
+	}
+}
+
+// test that passphrases can't be formatted into logs
+func Test_Passphrase_redacted(t *testing.T) {
+
+	incoming := IncomingUnlock{Passphrase: "correct horse", Timeout: 60}
+
+	assert.NotContains(t, fmt.Sprintf("%v %+v %#v %s", incoming, incoming, incoming, incoming.Passphrase), "correct horse")
+
+}
+
+// test the unlock timeout rules
+func Test_validateUnlock(t *testing.T) {
+
+	assert.Nil(t, validateUnlock(IncomingUnlock{Passphrase: "p", Timeout: 60}))
+	assert.Nil(t, validateUnlock(IncomingUnlock{Passphrase: "p", StakingOnly: true}))
+	assert.NotNil(t, validateUnlock(IncomingUnlock{Passphrase: "p"}))
+	assert.NotNil(t, validateUnlock(IncomingUnlock{Passphrase: "p", Timeout: -1}))
+	assert.NotNil(t, validateUnlock(IncomingUnlock{Timeout: 60}))
+
+}
+
+// test that the staking only flag and timeout are passed to the daemon
+func Test_unlockWallet(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	calls := map[string][]interface{}{}
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", mockParamsResponder(calls))
+
+	status, err := unlockWallet(rpctest.CoinData(), IncomingUnlock{Passphrase: "p", StakingOnly: true})
+
+	assert.Nil(t, err)
+	assert.True(t, status.Locked)
+	assert.True(t, status.StakingOnly)
+	assert.Equal(t, []interface{}{"p", float64(0), true}, calls["walletpassphrase"])
+
+}
+
+// test that a passphrase change reports the lock state the daemon has
+func Test_changePassphrase(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	incoming := IncomingPassphraseChange{OldPassphrase: "old", NewPassphrase: "new"}
+
+	for unlockedUntil, locked := range map[string]bool{"0": true, "1463088112": false} {
+
+		httpmock.RegisterResponder("POST", "http://127.0.0.1:0", rpctest.Responder(map[string]string{
+			"walletpassphrasechange": `{"result": null, "error": null, "id": null}`,
+			"getwalletinfo":          `{"result": {"walletversion": 130000, "unlocked_until": ` + unlockedUntil + `}, "error": null, "id": null}`,
+		}))
+
+		status, err := changePassphrase(rpctest.CoinData(), incoming)
+
+		assert.Nil(t, err)
+		assert.True(t, status.Encrypted)
+		assert.Equal(t, locked, status.Locked, unlockedUntil)
+
+	}
+
+}
+
+// test that encrypting the wallet has the daemon restarted
+func Test_encryptWallet(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	calls := map[string][]interface{}{}
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", mockParamsResponder(calls))
+
+	restarted := make(chan string, 1)
+
+	defer func(restart func(conf.CoinData, time.Duration) error) { restartDaemon = restart }(restartDaemon)
+
+	restartDaemon = func(coinData conf.CoinData, timeout time.Duration) error {
+		restarted <- coinData.CurrencyCode
+		return nil
+	}
+
+	status, err := encryptWallet(rpctest.CoinData(), "p")
+
+	assert.Nil(t, err)
+	assert.True(t, status.Encrypted)
+	assert.True(t, status.Restarting)
+	assert.Equal(t, []interface{}{"p"}, calls["encryptwallet"])
+
+	select {
+	case currency := <-restarted:
+		assert.Equal(t, rpctest.CoinData().CurrencyCode, currency)
+	case <-time.After(time.Second):
+		t.Error("daemon was not restarted")
+	}
+
+}
+
+// logLines sends each log line written to it
+type logLines chan string
+
+func (lines logLines) Write(p []byte) (int, error) {
+	lines <- string(p)
+	return len(p), nil
+}
+
+// test that a daemon failing to come back after encrypting is logged rather than ending the server
+func Test_encryptWalletRestartFails(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", mockParamsResponder(map[string][]interface{}{}))
+
+	defer func(restart func(conf.CoinData, time.Duration) error) { restartDaemon = restart }(restartDaemon)
+
+	restartDaemon = func(coinData conf.CoinData, timeout time.Duration) error {
+		return fmt.Errorf("exec: no such file")
+	}
+
+	lines := make(logLines, 1)
+	log.SetOutput(lines)
+	defer log.SetOutput(os.Stderr)
+
+	status, err := encryptWallet(rpctest.CoinData(), "p")
+
+	assert.Nil(t, err)
+	assert.True(t, status.Restarting)
+
+	select {
+	case line := <-lines:
+		assert.Contains(t, line, "Failed to restart the "+rpctest.CoinData().CurrencyCode+" daemon after encrypting its wallet: exec: no such file")
+	case <-time.After(time.Second):
+		t.Error("the failed restart was not logged")
+	}
+
+}
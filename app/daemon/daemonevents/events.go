@@ -0,0 +1,145 @@
+package daemonevents
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the daemons
+const (
+	EventBlock = "block"
+	EventTx    = "tx"
+)
+
+// subscriberBuffer is the number of events queued for a slow subscriber
+const subscriberBuffer = 256
+
+// TxDelay is how long tx events are gathered by Coalesce, so a busy
+// mempool costs subscribers one check per delay rather than one per tx
+var TxDelay = 2 * time.Second
+
+// Event is a notification that a coin's daemon has seen a new block or transaction
+type Event struct {
+	Type     string
+	Currency string
+	Hash     string
+	Raw      []byte
+	Sequence uint32
+}
+
+// Stream fans published events out to every subscriber
+type Stream struct {
+	mu   sync.Mutex
+	subs map[chan Event]bool
+}
+
+// Events is the server wide stream of daemon events
+var Events = NewStream()
+
+// NewStream creates an empty stream
+func NewStream() *Stream {
+	return &Stream{subs: make(map[chan Event]bool)}
+}
+
+// Subscribe returns a channel receiving every event published from now on
+func (s *Stream) Subscribe() chan Event {
+
+	ch := make(chan Event, subscriberBuffer)
+
+	s.mu.Lock()
+	s.subs[ch] = true
+	s.mu.Unlock()
+
+	return ch
+
+}
+
+// Unsubscribe stops and closes the subscriber's channel
+func (s *Stream) Unsubscribe(ch chan Event) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subs[ch] {
+		delete(s.subs, ch)
+		close(ch)
+	}
+
+}
+
+// Publish sends the event to every subscriber without blocking
+// subscribers that have fallen behind miss the event
+func (s *Stream) Publish(ev Event) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+
+}
+
+// Coalesce passes block events on as they arrive and holds tx events for
+// TxDelay, passing on the latest for each currency that had any
+// a currency's held tx event is dropped when one of its blocks arrives,
+// as subscribers check the mempool along with the new block
+// the returned channel is closed once events is
+func Coalesce(events <-chan Event) <-chan Event {
+
+	out := make(chan Event, subscriberBuffer)
+
+	send := func(ev Event) {
+		select {
+		case out <- ev:
+		default:
+		}
+	}
+
+	go func() {
+
+		defer close(out)
+
+		held := make(map[string]Event)
+		var flush <-chan time.Time
+
+		for {
+			select {
+			case ev, ok := <-events:
+
+				if !ok {
+					return
+				}
+
+				if ev.Type != EventTx {
+					delete(held, ev.Currency)
+					send(ev)
+					continue
+				}
+
+				if flush == nil {
+					flush = time.After(TxDelay)
+				}
+
+				held[ev.Currency] = ev
+
+			case <-flush:
+
+				for _, ev := range held {
+					send(ev)
+				}
+
+				held = make(map[string]Event)
+				flush = nil
+
+			}
+		}
+
+	}()
+
+	return out
+
+}
@@ -0,0 +1,46 @@
+package daemonevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test that blocks pass straight through and a burst of txs is passed on once per currency
+func Test_Coalesce(t *testing.T) {
+
+	TxDelay = 50 * time.Millisecond
+
+	events := make(chan Event)
+	coalesced := Coalesce(events)
+
+	for i := 0; i < 10; i++ {
+		events <- Event{Type: EventTx, Currency: "NAV", Hash: "tx"}
+	}
+	events <- Event{Type: EventTx, Currency: "NAV", Hash: "last"}
+	events <- Event{Type: EventTx, Currency: "BTC", Hash: "tx"}
+	events <- Event{Type: EventTx, Currency: "DASH", Hash: "tx"}
+
+	// a block covers its currency's mempool
+	events <- Event{Type: EventBlock, Currency: "DASH", Hash: "block"}
+	assert.Equal(t, Event{Type: EventBlock, Currency: "DASH", Hash: "block"}, <-coalesced)
+
+	received := map[string]string{}
+	for i := 0; i < 2; i++ {
+		ev := <-coalesced
+		received[ev.Currency] = ev.Hash
+	}
+	assert.Equal(t, map[string]string{"NAV": "last", "BTC": "tx"}, received)
+
+	select {
+	case ev := <-coalesced:
+		t.Errorf("unexpected %s event for %s", ev.Type, ev.Currency)
+	case <-time.After(2 * TxDelay):
+	}
+
+	close(events)
+	_, open := <-coalesced
+	assert.False(t, open)
+
+}
@@ -0,0 +1,230 @@
+package daemonhistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+const testAddress = "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"
+
+// mockChain is a daemon's chain of block hashes and address deltas
+type mockChain struct {
+	mu     sync.Mutex
+	hashes []string
+	deltas []addressDelta
+}
+
+// extend replaces the chain above height with the blocks named by the prefix
+func (chain *mockChain) extend(height int, to int, prefix string) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	chain.hashes = chain.hashes[:height]
+	for h := height + 1; h <= to; h++ {
+		chain.hashes = append(chain.hashes, fmt.Sprintf("%s%d", prefix, h))
+	}
+	deltas := []addressDelta{}
+	for _, delta := range chain.deltas {
+		if delta.Height <= int64(height) {
+			deltas = append(deltas, delta)
+		}
+	}
+	chain.deltas = deltas
+}
+
+// pay adds a delta for the test address at the height
+func (chain *mockChain) pay(height int64, txID string) {
+	chain.mu.Lock()
+	chain.deltas = append(chain.deltas, addressDelta{TxID: txID, Height: height, Address: testAddress})
+	chain.mu.Unlock()
+}
+
+// responder answers the RPC calls the store makes from the chain
+func (chain *mockChain) responder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+
+		rpcReq := struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		json.NewDecoder(req.Body).Decode(&rpcReq)
+
+		chain.mu.Lock()
+		defer chain.mu.Unlock()
+
+		var result interface{}
+
+		switch rpcReq.Method {
+		case "getblockcount":
+			result = len(chain.hashes)
+		case "getblockhash":
+			var h int
+			json.Unmarshal(rpcReq.Params[0], &h)
+			result = chain.hashes[h-1]
+		case "getaddressdeltas":
+			params := deltaParams{}
+			json.Unmarshal(rpcReq.Params[0], &params)
+			deltas := []addressDelta{}
+			for _, delta := range chain.deltas {
+				if delta.Height >= params.Start && delta.Height <= params.End {
+					deltas = append(deltas, delta)
+				}
+			}
+			result = deltas
+		}
+
+		return httpmock.NewJsonResponse(200, map[string]interface{}{"result": result, "error": nil})
+
+	}
+}
+
+// newTestStore opens a store in a temporary directory
+func newTestStore(t *testing.T) (*Store, func()) {
+
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+
+	store, err := Open(rpctest.CoinData(), filepath.Join(dir, "nav-history.db"))
+	assert.Nil(t, err)
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+
+}
+
+// txIDs returns the txids of the test address's history
+func txIDs(t *testing.T, store *Store) []string {
+
+	entries, err := store.History(testAddress)
+	assert.Nil(t, err)
+
+	ids := []string{}
+	for _, entry := range entries {
+		ids = append(ids, entry.TxID)
+	}
+
+	return ids
+
+}
+
+// test that history is imported, kept up to date and rolled back on a reorg
+func Test_Sync(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	chain := &mockChain{}
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", chain.responder())
+
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	chain.extend(0, 10, "a")
+	chain.pay(3, "t3")
+	chain.pay(8, "t8")
+
+	_, err := store.History(testAddress)
+	assert.Equal(t, ErrNotSynced, err)
+
+	assert.Nil(t, store.Sync())
+	assert.Equal(t, int64(10), store.Tip())
+	assert.Equal(t, []string{"t3", "t8"}, txIDs(t, store))
+
+	// new blocks only add their own deltas
+	chain.extend(10, 12, "a")
+	chain.pay(12, "t12")
+
+	assert.Nil(t, store.Sync())
+	assert.Equal(t, int64(12), store.Tip())
+	assert.Equal(t, []string{"t3", "t8", "t12"}, txIDs(t, store))
+
+	// blocks 11 and 12 are replaced by a longer fork
+	chain.extend(10, 13, "b")
+	chain.pay(13, "t13")
+
+	assert.Nil(t, store.Sync())
+	assert.Equal(t, int64(13), store.Tip())
+	assert.Equal(t, "b13", store.blockHash(13))
+	assert.Equal(t, []string{"t3", "t8", "t13"}, txIDs(t, store))
+
+	// the chain is replaced by a shorter fork
+	chain.extend(7, 9, "c")
+
+	assert.Nil(t, store.Sync())
+	assert.Equal(t, int64(9), store.Tip())
+	assert.Equal(t, []string{"t3"}, txIDs(t, store))
+
+}
+
+// test that only the most recent block hashes are kept
+func Test_addBlocks(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	chain := &mockChain{}
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", chain.responder())
+
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	chain.extend(0, 250, "a")
+
+	assert.Nil(t, store.addBlocks(nil, 1, 250))
+
+	assert.Equal(t, int64(250), store.Tip())
+	assert.Equal(t, "a250", store.blockHash(250))
+	assert.Equal(t, "a151", store.blockHash(151))
+	assert.Equal(t, "", store.blockHash(150))
+
+}
+
+// test that the least recently requested addresses are forgotten past the cap and after the expiry
+func Test_trackedLimits(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	chain := &mockChain{}
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", chain.responder())
+
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	defer func(max int) { maxTracked = max }(maxTracked)
+	maxTracked = 2
+
+	chain.extend(0, 10, "a")
+	assert.Nil(t, store.Sync())
+
+	for _, address := range []string{"a1", "a2"} {
+		_, err := store.History(address)
+		assert.Nil(t, err)
+	}
+
+	// a1 is requested again so a2 is the least recently requested
+	_, err := store.History("a1")
+	assert.Nil(t, err)
+
+	_, err = store.History("a3")
+	assert.Nil(t, err)
+
+	assert.ElementsMatch(t, []string{"a1", "a3"}, store.trackedAddresses())
+
+	// addresses not requested since the expiry are forgotten
+	assert.Nil(t, store.expire(time.Now().Add(time.Hour)))
+	assert.Empty(t, store.trackedAddresses())
+
+}
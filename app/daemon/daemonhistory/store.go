@@ -0,0 +1,434 @@
+package daemonhistory
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the history store
+// history holds a nested bucket per tracked address keyed by height and txid
+// blocks holds the hashes of recent blocks by height for detecting reorgs
+// requested holds when each tracked address was last requested
+var (
+	bucketMeta      = []byte("meta")
+	bucketBlocks    = []byte("blocks")
+	bucketHistory   = []byte("history")
+	bucketRequested = []byte("requested")
+
+	keyTip = []byte("tip")
+)
+
+// maxReorgDepth is the number of recent block hashes kept to find a fork point
+const maxReorgDepth = 100
+
+// maxTracked bounds the addresses kept up to date, the least recently
+// requested is forgotten to make room, and trackedExpiry forgets addresses
+// not requested for that long, so callers can't grow the store without limit
+var (
+	maxTracked    = 1000
+	trackedExpiry = 7 * 24 * time.Hour
+)
+
+// ErrNotSynced is returned when history is requested before the store has a tip
+var ErrNotSynced = errors.New("history store has not synced a tip yet")
+
+// Entry is a transaction in an address's history
+type Entry struct {
+	TxID   string `json:"txid"`
+	Height int64  `json:"height"`
+}
+
+// Store is a coin's local address history, kept in step with the daemon
+// one block at a time so history requests don't have to rebuild it
+type Store struct {
+	coinData conf.CoinData
+	db       *bolt.DB
+
+	// mu serialises syncing and importing newly tracked addresses
+	mu sync.Mutex
+}
+
+// addressDelta is the part of a 'getaddressdeltas' entry the store needs
+type addressDelta struct {
+	TxID    string `json:"txid"`
+	Height  int64  `json:"height"`
+	Address string `json:"address"`
+}
+
+// deltaParams are the params for the 'getaddressdeltas' RPC call
+type deltaParams struct {
+	Addresses []string `json:"addresses"`
+	Start     int64    `json:"start"`
+	End       int64    `json:"end"`
+}
+
+// stores are the open history stores by currency code
+var stores = struct {
+	sync.Mutex
+	byCurrency map[string]*Store
+}{byCurrency: make(map[string]*Store)}
+
+// Open opens, creating if needed, the coin's history store at path
+func Open(coinData conf.CoinData, path string) (*Store, error) {
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketMeta, bucketBlocks, bucketHistory, bucketRequested} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{coinData: coinData, db: db}, nil
+
+}
+
+// Register makes the store the one used for its coin's history requests
+func Register(store *Store) {
+	stores.Lock()
+	stores.byCurrency[store.coinData.CurrencyCode] = store
+	stores.Unlock()
+}
+
+// Unregister removes the currency's store, its history requests fail until another is registered
+func Unregister(currency string) {
+	stores.Lock()
+	delete(stores.byCurrency, currency)
+	stores.Unlock()
+}
+
+// Get returns the registered store for the currency, or nil if there is none
+func Get(currency string) *Store {
+	stores.Lock()
+	defer stores.Unlock()
+	return stores.byCurrency[currency]
+}
+
+// Close closes the store's database
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// Tip returns the height the store is synced to, 0 before the first sync
+func (store *Store) Tip() int64 {
+
+	var tip int64
+
+	store.db.View(func(tx *bolt.Tx) error {
+		tip = decodeHeight(tx.Bucket(bucketMeta).Get(keyTip))
+		return nil
+	})
+
+	return tip
+
+}
+
+// History returns the confirmed transactions of the address in block order
+// an address seen for the first time has its history imported from the daemon
+// and is then kept up to date as new blocks arrive, until it is forgotten
+func (store *Store) History(address string) ([]Entry, error) {
+
+	if !store.tracked(address) {
+		if err := store.track(address); err != nil {
+			return nil, err
+		}
+	} else if err := store.touch(address); err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(bucketHistory).Bucket([]byte(address))
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			height, txID := decodeHistoryKey(k)
+			entries = append(entries, Entry{TxID: txID, Height: height})
+			return nil
+		})
+
+	})
+
+	return entries, err
+
+}
+
+// tracked reports whether the address's history is kept by the store
+func (store *Store) tracked(address string) bool {
+
+	tracked := false
+
+	store.db.View(func(tx *bolt.Tx) error {
+		tracked = tx.Bucket(bucketHistory).Bucket([]byte(address)) != nil
+		return nil
+	})
+
+	return tracked
+
+}
+
+// track imports the address's history up to the store's tip
+func (store *Store) track(address string) error {
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	tip := store.Tip()
+
+	if tip == 0 {
+		return ErrNotSynced
+	}
+
+	deltas := []addressDelta{}
+	params := deltaParams{Addresses: []string{address}, Start: 1, End: tip}
+
+	if err := daemonrpc.CallDaemon(store.coinData, "getaddressdeltas", []interface{}{params}, &deltas); err != nil {
+		return err
+	}
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+
+		bucket, err := tx.Bucket(bucketHistory).CreateBucketIfNotExists([]byte(address))
+
+		if err != nil {
+			return err
+		}
+
+		for _, delta := range deltas {
+			if err := bucket.Put(historyKey(delta.Height, delta.TxID), []byte{}); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Bucket(bucketRequested).Put([]byte(address), encodeTime(time.Now())); err != nil {
+			return err
+		}
+
+		return evictOldest(tx, []byte(address))
+
+	})
+
+}
+
+// touch records that the tracked address was requested
+func (store *Store) touch(address string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRequested).Put([]byte(address), encodeTime(time.Now()))
+	})
+}
+
+// evictOldest forgets the least recently requested addresses past maxTracked, never keep
+func evictOldest(tx *bolt.Tx, keep []byte) error {
+
+	requested := tx.Bucket(bucketRequested)
+
+	for {
+
+		count := 0
+		oldest, oldestAt := []byte(nil), time.Time{}
+
+		requested.ForEach(func(k, v []byte) error {
+			count++
+			if at := decodeTime(v); string(k) != string(keep) && (oldest == nil || at.Before(oldestAt)) {
+				oldest, oldestAt = append([]byte{}, k...), at
+			}
+			return nil
+		})
+
+		if count <= maxTracked || oldest == nil {
+			return nil
+		}
+
+		if err := forget(tx, oldest); err != nil {
+			return err
+		}
+
+	}
+
+}
+
+// expire forgets the addresses not requested since before
+func (store *Store) expire(before time.Time) error {
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+
+		requested := tx.Bucket(bucketRequested)
+		expired := [][]byte{}
+
+		requested.ForEach(func(k, v []byte) error {
+			if decodeTime(v).Before(before) {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		})
+
+		for _, address := range expired {
+			if err := forget(tx, address); err != nil {
+				return err
+			}
+		}
+
+		// addresses tracked before requests were recorded expire from now
+		return tx.Bucket(bucketHistory).ForEach(func(k, v []byte) error {
+			if requested.Get(k) == nil {
+				return requested.Put(append([]byte{}, k...), encodeTime(time.Now()))
+			}
+			return nil
+		})
+
+	})
+
+}
+
+// forget stops tracking the address and removes its history
+func forget(tx *bolt.Tx, address []byte) error {
+
+	if err := tx.Bucket(bucketRequested).Delete(address); err != nil {
+		return err
+	}
+
+	if tx.Bucket(bucketHistory).Bucket(address) == nil {
+		return nil
+	}
+
+	return tx.Bucket(bucketHistory).DeleteBucket(address)
+
+}
+
+// trackedAddresses returns every address the store keeps history for
+func (store *Store) trackedAddresses() []string {
+
+	addresses := []string{}
+
+	store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketHistory).ForEach(func(k, v []byte) error {
+			addresses = append(addresses, string(k))
+			return nil
+		})
+	})
+
+	return addresses
+
+}
+
+// blockHash returns the stored hash of the block at height, if it is kept
+func (store *Store) blockHash(height int64) string {
+
+	hash := ""
+
+	store.db.View(func(tx *bolt.Tx) error {
+		hash = string(tx.Bucket(bucketBlocks).Get(encodeHeight(height)))
+		return nil
+	})
+
+	return hash
+
+}
+
+// rollback removes every history entry and block hash above height
+func (store *Store) rollback(height int64) error {
+
+	log.Printf("Rolling back %s history store to height %d", store.coinData.CurrencyCode, height)
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+
+		history := tx.Bucket(bucketHistory)
+
+		// the addresses are collected first as bolt buckets
+		// must not be changed while they are iterated
+		addresses := [][]byte{}
+
+		history.ForEach(func(k, v []byte) error {
+			addresses = append(addresses, append([]byte{}, k...))
+			return nil
+		})
+
+		for _, address := range addresses {
+			if err := deleteFrom(history.Bucket(address), encodeHeight(height+1)); err != nil {
+				return err
+			}
+		}
+
+		if err := deleteFrom(tx.Bucket(bucketBlocks), encodeHeight(height+1)); err != nil {
+			return err
+		}
+
+		return tx.Bucket(bucketMeta).Put(keyTip, encodeHeight(height))
+
+	})
+
+}
+
+// deleteFrom deletes every key in the bucket from start onwards
+func deleteFrom(bucket *bolt.Bucket, start []byte) error {
+
+	c := bucket.Cursor()
+
+	for k, _ := c.Seek(start); k != nil; k, _ = c.Seek(start) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// historyKey orders entries by height and then txid
+func historyKey(height int64, txID string) []byte {
+	return append(encodeHeight(height), txID...)
+}
+
+// decodeHistoryKey splits a history key into its height and txid
+func decodeHistoryKey(k []byte) (int64, string) {
+	return decodeHeight(k[:8]), string(k[8:])
+}
+
+// encodeHeight encodes the height big endian so keys sort by height
+func encodeHeight(height int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(height))
+	return b
+}
+
+// encodeTime encodes the time as big endian unix nanoseconds
+func encodeTime(t time.Time) []byte {
+	return encodeHeight(t.UnixNano())
+}
+
+// decodeTime decodes a time encoded by encodeTime
+func decodeTime(b []byte) time.Time {
+	return time.Unix(0, decodeHeight(b))
+}
+
+// decodeHeight decodes a big endian height, 0 if it is missing
+func decodeHeight(b []byte) int64 {
+	if len(b) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,206 @@
+package daemonhistory
+
+import (
+	"log"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonevents"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	bolt "go.etcd.io/bbolt"
+)
+
+// pollInterval is how often the store checks for new blocks without a notification
+var pollInterval = 30 * time.Second
+
+// Run syncs the store whenever the daemon notifies us of a new block,
+// polling as a fallback, until stop is closed
+func (store *Store) Run(stop <-chan struct{}) {
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	events := daemonevents.Events.Subscribe()
+	defer daemonevents.Events.Unsubscribe(events)
+
+	store.syncAndLog()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case ev := <-events:
+			if ev.Type == daemonevents.EventBlock && ev.Currency == store.coinData.CurrencyCode {
+				store.syncAndLog()
+			}
+		case <-ticker.C:
+			store.syncAndLog()
+		}
+	}
+
+}
+
+// syncAndLog syncs the store, logging any failure
+func (store *Store) syncAndLog() {
+	if err := store.Sync(); err != nil {
+		log.Println("Failed to sync " + store.coinData.CurrencyCode + " history store: " + err.Error())
+	}
+}
+
+// Sync brings the store up to the daemon's tip, first rolling back any
+// blocks that are no longer on the best chain
+func (store *Store) Sync() error {
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var height int64
+
+	if err := daemonrpc.CallDaemon(store.coinData, "getblockcount", nil, &height); err != nil {
+		return err
+	}
+
+	tip := store.Tip()
+
+	// a new store starts from the current tip, addresses are
+	// imported in full when they are first requested
+	if tip == 0 {
+		return store.addBlocks(nil, 1, height)
+	}
+
+	// the chain may have been replaced by a shorter one
+	if tip > height {
+		if err := store.rollback(height); err != nil {
+			return err
+		}
+		tip = height
+	}
+
+	fork, err := store.findFork(tip)
+
+	if err != nil {
+		return err
+	}
+
+	if fork < tip {
+		if err := store.rollback(fork); err != nil {
+			return err
+		}
+		tip = fork
+	}
+
+	if height == tip {
+		return nil
+	}
+
+	if err := store.expire(time.Now().Add(-trackedExpiry)); err != nil {
+		return err
+	}
+
+	deltas := []addressDelta{}
+	addresses := store.trackedAddresses()
+
+	if len(addresses) > 0 {
+		params := deltaParams{Addresses: addresses, Start: tip + 1, End: height}
+		if err := daemonrpc.CallDaemon(store.coinData, "getaddressdeltas", []interface{}{params}, &deltas); err != nil {
+			return err
+		}
+	}
+
+	return store.addBlocks(deltas, tip+1, height)
+
+}
+
+// findFork walks back from height to the highest block whose stored
+// hash still matches the daemon's best chain
+func (store *Store) findFork(height int64) (int64, error) {
+
+	for h := height; h > 0 && h > height-maxReorgDepth; h-- {
+
+		stored := store.blockHash(h)
+
+		// blocks older than the kept hashes are assumed to be final
+		if stored == "" {
+			return h, nil
+		}
+
+		hash := ""
+
+		if err := daemonrpc.CallDaemon(store.coinData, "getblockhash", []interface{}{h}, &hash); err != nil {
+			return 0, err
+		}
+
+		if hash == stored {
+			return h, nil
+		}
+
+	}
+
+	log.Printf("%s reorg is deeper than the %d blocks kept by the history store", store.coinData.CurrencyCode, maxReorgDepth)
+
+	return height - maxReorgDepth, nil
+
+}
+
+// addBlocks stores the deltas of the blocks from start to end along with
+// the hashes needed to detect a reorg of them, and moves the tip to end
+func (store *Store) addBlocks(deltas []addressDelta, start int64, end int64) error {
+
+	// only the most recent hashes are needed to find a fork
+	if end-start >= maxReorgDepth {
+		start = end - maxReorgDepth + 1
+	}
+
+	hashes := make(map[int64]string)
+
+	for h := start; h <= end; h++ {
+
+		hash := ""
+
+		if err := daemonrpc.CallDaemon(store.coinData, "getblockhash", []interface{}{h}, &hash); err != nil {
+			return err
+		}
+
+		hashes[h] = hash
+
+	}
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+
+		history := tx.Bucket(bucketHistory)
+
+		for _, delta := range deltas {
+
+			bucket := history.Bucket([]byte(delta.Address))
+
+			// skip any address that is not tracked
+			if bucket == nil {
+				continue
+			}
+
+			if err := bucket.Put(historyKey(delta.Height, delta.TxID), []byte{}); err != nil {
+				return err
+			}
+
+		}
+
+		blocks := tx.Bucket(bucketBlocks)
+
+		for h, hash := range hashes {
+			if err := blocks.Put(encodeHeight(h), []byte(hash)); err != nil {
+				return err
+			}
+		}
+
+		// forget hashes too old to be needed
+		c := blocks.Cursor()
+		for k, _ := c.First(); k != nil && decodeHeight(k) <= end-maxReorgDepth; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+
+		return tx.Bucket(bucketMeta).Put(keyTip, encodeHeight(end))
+
+	})
+
+}
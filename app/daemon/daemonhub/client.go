@@ -0,0 +1,241 @@
+package daemonhub
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/address"
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Subscription actions a client can send
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+const (
+	// writeWait is the time allowed to write a message to the client
+	writeWait = 10 * time.Second
+
+	// pongWait is the time allowed to read the next pong from the client
+	pongWait = 60 * time.Second
+
+	// pingPeriod sends pings to the client, must be less than pongWait
+	pingPeriod = (pongWait * 9) / 10
+
+	// sendBuffer is the number of messages queued for a slow client
+	sendBuffer = 64
+)
+
+// upgrader accepts any origin as routes are protected by the API token
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// Subscription is sent by clients to change what they are pushed
+type Subscription struct {
+	Action    string   `json:"action"`
+	Blocks    bool     `json:"blocks"`
+	Addresses []string `json:"addresses"`
+}
+
+// Client is a single websocket connection subscribed to a hub
+type Client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	send chan Message
+
+	mu        sync.Mutex
+	blocks    bool
+	addresses map[string]bool
+}
+
+// InitHubHandlers sets up the websocket subscription endpoint for the hub's coin
+func InitHubHandlers(r *mux.Router, hub *Hub, prefix string) {
+
+	namespace := "ws"
+
+	// subscribe endpoint :: upgrades to a websocket pushing blocks and address activity
+	subscribePath := api.CoinRouteBuilder(prefix, namespace, "v1", hub.coinData, "subscribe")
+	api.ProtectedRouteHandler(subscribePath, r, subscribeHandler(hub), http.MethodGet)
+
+}
+
+// subscribeHandler upgrades the request and serves the client until it disconnects
+func subscribeHandler(hub *Hub) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+
+		if err != nil {
+			log.Println("Failed to upgrade " + hub.coinData.CurrencyCode + " websocket: " + err.Error())
+			return
+		}
+
+		client := &Client{
+			hub:       hub,
+			conn:      conn,
+			send:      make(chan Message, sendBuffer),
+			addresses: make(map[string]bool),
+		}
+
+		hub.register(client)
+
+		go client.writePump()
+		client.readPump()
+
+	})
+}
+
+// readPump applies subscriptions sent by the client until the connection closes
+func (client *Client) readPump() {
+
+	defer func() {
+		client.hub.unregister(client)
+		close(client.send)
+	}()
+
+	client.conn.SetReadDeadline(time.Now().Add(pongWait))
+	client.conn.SetPongHandler(func(string) error {
+		client.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+
+		sub := Subscription{}
+
+		if err := client.conn.ReadJSON(&sub); err != nil {
+			return
+		}
+
+		if err := client.apply(sub); err != nil {
+			client.hub.mu.Lock()
+			client.push(Message{Type: MessageError, Currency: client.hub.coinData.CurrencyCode, Error: err.Error()})
+			client.hub.mu.Unlock()
+		}
+
+	}
+
+}
+
+// writePump sends queued messages and keeps the connection alive with pings
+func (client *Client) writePump() {
+
+	ticker := time.NewTicker(pingPeriod)
+
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+
+	for {
+		select {
+
+		case msg, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := client.conn.WriteJSON(msg); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		}
+	}
+
+}
+
+// apply validates the subscription's addresses and updates the client
+func (client *Client) apply(sub Subscription) error {
+
+	coinData := client.hub.coinData
+
+	if address.IsSupported(coinData.CurrencyCode) {
+		for _, addressStr := range sub.Addresses {
+			if err := address.Validate(coinData.CurrencyCode, addressStr, coinData.UseTestNet); err != nil {
+				return err
+			}
+		}
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	subscribe := sub.Action != ActionUnsubscribe
+
+	if sub.Blocks {
+		client.blocks = subscribe
+	}
+
+	for _, addressStr := range sub.Addresses {
+		if subscribe {
+			client.addresses[addressStr] = true
+		} else {
+			delete(client.addresses, addressStr)
+		}
+	}
+
+	return nil
+
+}
+
+// push queues the message, dropping it if the client is too slow to keep up
+// the hub lock must be held so the send channel is not closed underneath us
+func (client *Client) push(msg Message) {
+	select {
+	case client.send <- msg:
+	default:
+		log.Println("Dropping " + msg.Type + " message for slow " + msg.Currency + " websocket client")
+	}
+}
+
+// wants reports whether the client is subscribed to the message
+func (client *Client) wants(msg Message) bool {
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if msg.Type == MessageBlock {
+		return client.blocks
+	}
+
+	return client.addresses[msg.Address]
+
+}
+
+// wantsBlocks reports whether the client is subscribed to new blocks
+func (client *Client) wantsBlocks() bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.blocks
+}
+
+// subscribedAddresses returns the addresses the client is subscribed to
+func (client *Client) subscribedAddresses() []string {
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	addresses := []string{}
+	for addressStr := range client.addresses {
+		addresses = append(addresses, addressStr)
+	}
+
+	return addresses
+
+}
@@ -0,0 +1,274 @@
+package daemonhub
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonevents"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+)
+
+// Message types pushed to subscribed clients
+const (
+	MessageBlock = "block"
+	MessageTx    = "tx"
+	MessageError = "error"
+)
+
+// pollInterval is how often the hub checks the daemon for changes
+var pollInterval = 5 * time.Second
+
+// Message is pushed to clients when the tip changes or a subscribed
+// address has a new unconfirmed or newly confirmed transaction
+type Message struct {
+	Type      string `json:"type"`
+	Currency  string `json:"currency"`
+	Height    int64  `json:"height,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Address   string `json:"address,omitempty"`
+	TxID      string `json:"txid,omitempty"`
+	Confirmed bool   `json:"confirmed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Hub tracks the clients subscribed to a single coin and pushes them
+// messages as the coin's daemon sees new blocks and transactions
+type Hub struct {
+	coinData conf.CoinData
+
+	mu        sync.Mutex
+	clients   map[*Client]bool
+	tipHash   string
+	tipHeight int64
+	pending   map[string]bool
+}
+
+// tipInfo is the part of 'getblockchaininfo' the hub needs
+type tipInfo struct {
+	Blocks        int64  `json:"blocks"`
+	BestBlockHash string `json:"bestblockhash"`
+}
+
+// mempoolEntry is the part of a 'getaddressmempool' entry the hub needs
+type mempoolEntry struct {
+	Address string `json:"address"`
+	TxID    string `json:"txid"`
+}
+
+// addressParams are the params for the address index RPC calls
+type addressParams struct {
+	Addresses []string `json:"addresses"`
+	Start     int64    `json:"start,omitempty"`
+	End       int64    `json:"end,omitempty"`
+}
+
+// NewHub creates the hub for a coin
+func NewHub(coinData conf.CoinData) *Hub {
+	return &Hub{
+		coinData: coinData,
+		clients:  make(map[*Client]bool),
+		pending:  make(map[string]bool),
+	}
+}
+
+// Run checks the daemon for changes whenever it notifies us of a new
+// block or transaction, polling as a fallback, until stop is closed
+// transactions are coalesced so a burst of them costs one check
+func (hub *Hub) Run(stop <-chan struct{}) {
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	events := daemonevents.Events.Subscribe()
+	defer daemonevents.Events.Unsubscribe(events)
+
+	coalesced := daemonevents.Coalesce(events)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case ev := <-coalesced:
+			if ev.Currency == hub.coinData.CurrencyCode {
+				hub.poll()
+			}
+		case <-ticker.C:
+			hub.poll()
+		}
+	}
+
+}
+
+// register adds a client to the hub
+func (hub *Hub) register(client *Client) {
+	hub.mu.Lock()
+	hub.clients[client] = true
+	hub.mu.Unlock()
+}
+
+// unregister removes a client from the hub
+func (hub *Hub) unregister(client *Client) {
+	hub.mu.Lock()
+	delete(hub.clients, client)
+	hub.mu.Unlock()
+}
+
+// poll checks the daemon for a new tip and new mempool transactions
+// nothing is requested while no client is subscribed to anything
+func (hub *Hub) poll() {
+
+	blocks, addresses := hub.subscriptions()
+
+	// forget the tip while idle so the next subscriber starts from the
+	// current one, not a scan of every block mined in the meantime
+	if !blocks && len(addresses) == 0 {
+		hub.mu.Lock()
+		hub.tipHash, hub.tipHeight = "", 0
+		hub.pending = make(map[string]bool)
+		hub.mu.Unlock()
+		return
+	}
+
+	if err := hub.checkTip(addresses); err != nil {
+		log.Println("Hub failed to check " + hub.coinData.CurrencyCode + " tip: " + err.Error())
+		return
+	}
+
+	if err := hub.checkMempool(addresses); err != nil {
+		log.Println("Hub failed to check " + hub.coinData.CurrencyCode + " mempool: " + err.Error())
+	}
+
+}
+
+// checkTip pushes a block message when the tip has moved and a confirmed
+// tx message for each subscribed address with txs in the new blocks
+func (hub *Hub) checkTip(addresses []string) error {
+
+	tip := tipInfo{}
+
+	if err := daemonrpc.CallDaemon(hub.coinData, "getblockchaininfo", nil, &tip); err != nil {
+		return err
+	}
+
+	hub.mu.Lock()
+	prevHash, prevHeight := hub.tipHash, hub.tipHeight
+	hub.tipHash, hub.tipHeight = tip.BestBlockHash, tip.Blocks
+	hub.mu.Unlock()
+
+	// the first poll only records where the chain is
+	if prevHash == "" || prevHash == tip.BestBlockHash {
+		return nil
+	}
+
+	hub.broadcast(Message{Type: MessageBlock, Height: tip.Blocks, Hash: tip.BestBlockHash})
+
+	// a reorg to the same or lower height rescans the new tip only
+	start := prevHeight + 1
+	if start > tip.Blocks {
+		start = tip.Blocks
+	}
+
+	for _, address := range addresses {
+
+		txIDs := []string{}
+		params := addressParams{Addresses: []string{address}, Start: start, End: tip.Blocks}
+
+		if err := daemonrpc.CallDaemon(hub.coinData, "getaddresstxids", []interface{}{params}, &txIDs); err != nil {
+			return err
+		}
+
+		for _, txID := range txIDs {
+			hub.broadcast(Message{Type: MessageTx, Address: address, TxID: txID, Confirmed: true, Height: tip.Blocks})
+		}
+
+	}
+
+	return nil
+
+}
+
+// checkMempool pushes an unconfirmed tx message the first time
+// a transaction for a subscribed address is seen in the mempool
+func (hub *Hub) checkMempool(addresses []string) error {
+
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	entries := []mempoolEntry{}
+	params := addressParams{Addresses: addresses}
+
+	if err := daemonrpc.CallDaemon(hub.coinData, "getaddressmempool", []interface{}{params}, &entries); err != nil {
+		return err
+	}
+
+	// the pending set is replaced each poll so txs that confirm or
+	// drop out of the mempool are forgotten
+	pending := make(map[string]bool)
+
+	hub.mu.Lock()
+	prevPending := hub.pending
+	hub.mu.Unlock()
+
+	for _, entry := range entries {
+
+		key := entry.Address + ":" + entry.TxID
+
+		if !prevPending[key] && !pending[key] {
+			hub.broadcast(Message{Type: MessageTx, Address: entry.Address, TxID: entry.TxID, Confirmed: false})
+		}
+
+		pending[key] = true
+
+	}
+
+	hub.mu.Lock()
+	hub.pending = pending
+	hub.mu.Unlock()
+
+	return nil
+
+}
+
+// subscriptions reports whether any client wants blocks and
+// returns the distinct addresses clients are subscribed to
+func (hub *Hub) subscriptions() (bool, []string) {
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	blocks := false
+	seen := make(map[string]bool)
+	addresses := []string{}
+
+	for client := range hub.clients {
+		blocks = blocks || client.wantsBlocks()
+		for _, address := range client.subscribedAddresses() {
+			if !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+	}
+
+	return blocks, addresses
+
+}
+
+// broadcast sends the message to every client subscribed to it
+func (hub *Hub) broadcast(msg Message) {
+
+	msg.Currency = hub.coinData.CurrencyCode
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for client := range hub.clients {
+		if client.wants(msg) {
+			client.push(msg)
+		}
+	}
+
+}
@@ -0,0 +1,161 @@
+package daemonhub
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+// mock data struct for get blockchain info response
+func mockTipResponseData(height int, hash string) string {
+	return fmt.Sprintf(`{"result": {"blocks": %d, "bestblockhash": "%s"}, "error": null}`, height, hash)
+}
+
+// newTestClient registers a client on the hub without a connection
+func newTestClient(hub *Hub) *Client {
+	client := &Client{hub: hub, send: make(chan Message, sendBuffer), addresses: make(map[string]bool)}
+	hub.register(client)
+	return client
+}
+
+// test that invalid addresses are rejected and subscriptions can be removed
+func Test_apply(t *testing.T) {
+
+	client := newTestClient(NewHub(rpctest.CoinData()))
+
+	err := client.apply(Subscription{Action: ActionSubscribe, Addresses: []string{"Bak7ahbZAA"}})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(client.subscribedAddresses()))
+
+	err = client.apply(Subscription{Action: ActionSubscribe, Blocks: true, Addresses: []string{"NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"}})
+
+	assert.Nil(t, err)
+	assert.True(t, client.wantsBlocks())
+	assert.Equal(t, []string{"NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"}, client.subscribedAddresses())
+
+	client.apply(Subscription{Action: ActionUnsubscribe, Blocks: true, Addresses: []string{"NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"}})
+
+	assert.False(t, client.wantsBlocks())
+	assert.Equal(t, 0, len(client.subscribedAddresses()))
+
+}
+
+// test that polling pushes tip changes, pending and confirmed transactions
+func Test_poll(t *testing.T) {
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	responses := map[string]string{
+		"getblockchaininfo": mockTipResponseData(100, "aa"),
+		"getaddressmempool": `{"result": [{"address": "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G", "txid": "t1"}], "error": null}`,
+		"getaddresstxids":   `{"result": ["t1"], "error": null}`,
+	}
+
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", rpctest.Responder(responses))
+
+	hub := NewHub(rpctest.CoinData())
+	client := newTestClient(hub)
+	client.apply(Subscription{Action: ActionSubscribe, Blocks: true, Addresses: []string{"NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"}})
+
+	// the first poll records the tip and reports the pending tx
+	hub.poll()
+
+	assert.Equal(t, 1, len(client.send))
+	msg := <-client.send
+	assert.Equal(t, MessageTx, msg.Type)
+	assert.Equal(t, "t1", msg.TxID)
+	assert.False(t, msg.Confirmed)
+
+	// a tx already pending is not reported again
+	hub.poll()
+
+	assert.Equal(t, 0, len(client.send))
+
+	// a new tip reports the block and the confirmed tx
+	responses["getblockchaininfo"] = mockTipResponseData(101, "bb")
+	responses["getaddressmempool"] = `{"result": [], "error": null}`
+
+	hub.poll()
+
+	assert.Equal(t, 2, len(client.send))
+	msg = <-client.send
+	assert.Equal(t, MessageBlock, msg.Type)
+	assert.Equal(t, int64(101), msg.Height)
+	assert.Equal(t, "NAV", msg.Currency)
+	msg = <-client.send
+	assert.Equal(t, MessageTx, msg.Type)
+	assert.True(t, msg.Confirmed)
+
+	// blocks mined while nobody is subscribed are not reported to the next subscriber
+	client.apply(Subscription{Action: ActionUnsubscribe, Blocks: true, Addresses: []string{"NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"}})
+	hub.poll()
+
+	responses["getblockchaininfo"] = mockTipResponseData(250, "cc")
+	client.apply(Subscription{Action: ActionSubscribe, Blocks: true, Addresses: []string{"NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"}})
+	hub.poll()
+
+	assert.Equal(t, 0, len(client.send))
+
+}
+
+// test that the websocket requires the token and pushes subscribed messages
+func Test_subscribeHandler(t *testing.T) {
+
+	serverConf := conf.CurrentServerConfig()
+	defer conf.SetServerConfig(serverConf)
+
+	withToken := serverConf
+	withToken.APIToken = "secret"
+	conf.SetServerConfig(withToken)
+
+	hub := NewHub(rpctest.CoinData())
+	router := mux.NewRouter()
+	InitHubHandlers(router, hub, "api")
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws/v1/nav/subscribe"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	conn.WriteJSON(Subscription{Action: ActionSubscribe, Blocks: true})
+
+	// wait for the subscription to be applied before broadcasting
+	for i := 0; i < 100; i++ {
+		if blocks, _ := hub.subscriptions(); blocks {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hub.broadcast(Message{Type: MessageBlock, Height: 5, Hash: "cc"})
+
+	msg := Message{}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	assert.Nil(t, conn.ReadJSON(&msg))
+	assert.Equal(t, MessageBlock, msg.Type)
+	assert.Equal(t, "cc", msg.Hash)
+
+}
@@ -0,0 +1,252 @@
+package daemonlog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// tailChunk is how much of a file is read at a time while looking for its last lines
+const tailChunk = 64 * 1024
+
+// Writer appends to a log file, rotating it to path.1 up to path.<keep>
+// once it grows past maxSize bytes
+type Writer struct {
+	path    string
+	maxSize int64
+	keep    int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewWriter opens the log file at path for appending, creating its directory
+func NewWriter(path string, maxSize int64, keep int) (*Writer, error) {
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	w := &Writer{path: path, maxSize: maxSize, keep: keep}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+
+}
+
+// Path returns the path of the current log file
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Write appends p to the log, rotating first if it would pass maxSize
+func (w *Writer) Write(p []byte) (int, error) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+
+}
+
+// Close closes the current log file
+func (w *Writer) Close() error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+
+}
+
+// open opens the log file for appending and records its size
+func (w *Writer) open() error {
+
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+
+	return nil
+
+}
+
+// rotate shifts the old logs along, dropping the oldest, and starts a new file
+func (w *Writer) rotate() error {
+
+	w.file.Close()
+
+	if w.keep <= 0 {
+		os.Remove(w.path)
+		return w.open()
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", w.path, w.keep))
+
+	for i := w.keep - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+
+	if err := os.Rename(w.path, w.path+".1"); err != nil {
+		return err
+	}
+
+	return w.open()
+
+}
+
+// Tail returns the last n lines of the file and its size, which is
+// where following it should continue from
+func Tail(path string, n int) ([]string, int64, error) {
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := info.Size()
+
+	// read back from the end until there are enough lines
+	offset := size
+	data := []byte{}
+
+	for offset > 0 && bytes.Count(data, []byte("\n")) <= n {
+
+		chunk := int64(tailChunk)
+		if chunk > offset {
+			chunk = offset
+		}
+
+		offset -= chunk
+
+		buf := make([]byte, chunk)
+
+		if _, err := file.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+
+		data = append(buf, data...)
+
+	}
+
+	lines := []string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, tailChunk), len(data)+1)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines, size, scanner.Err()
+
+}
+
+// Follow copies whatever is appended to the file after offset to out,
+// checking every interval until stop is closed. A file that is replaced
+// or shrinks has been rotated and is followed from its start
+func Follow(path string, offset int64, out io.Writer, interval time.Duration, stop <-chan struct{}) error {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := os.Stat(path)
+
+	for {
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if info.Size() < offset || (last != nil && !os.SameFile(last, info)) {
+			offset = 0
+		}
+
+		last = info
+
+		if info.Size() == offset {
+			continue
+		}
+
+		if offset, err = copyFrom(path, offset, out); err != nil {
+			return err
+		}
+
+	}
+
+}
+
+// copyFrom copies the file from offset to out and returns the new offset
+func copyFrom(path string, offset int64, out io.Writer) (int64, error) {
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return offset, err
+	}
+
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+
+	n, err := io.Copy(out, file)
+
+	return offset + n, err
+
+}
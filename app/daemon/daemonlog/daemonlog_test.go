@@ -0,0 +1,159 @@
+package daemonlog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test that the log rotates past its max size, keeping only keep old files
+func Test_Writer_rotate(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "daemonlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "nav-daemon.log")
+
+	w, err := NewWriter(path, 10, 2)
+	assert.Nil(t, err)
+
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+
+	w.Close()
+
+	current, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "line 3\n", string(current))
+
+	older, _ := ioutil.ReadFile(path + ".2")
+	assert.Equal(t, "line 1\n", string(older))
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	// reopening appends to the current file
+	w, err = NewWriter(path, 100, 2)
+	assert.Nil(t, err)
+
+	w.Write([]byte("line 4\n"))
+	w.Close()
+
+	current, _ = ioutil.ReadFile(path)
+	assert.Equal(t, "line 3\nline 4\n", string(current))
+
+}
+
+// test that the last lines are read back from the end of a large file
+func Test_Tail(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "daemonlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "debug.log")
+
+	buf := &bytes.Buffer{}
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(buf, "2018-07-01 00:00:00 UpdateTip: height=%d\n", i)
+	}
+
+	ioutil.WriteFile(path, buf.Bytes(), 0644)
+
+	lines, size, err := Tail(path, 3)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), size)
+	assert.Equal(t, []string{
+		"2018-07-01 00:00:00 UpdateTip: height=19997",
+		"2018-07-01 00:00:00 UpdateTip: height=19998",
+		"2018-07-01 00:00:00 UpdateTip: height=19999",
+	}, lines)
+
+	lines, _, err = Tail(path, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(lines))
+
+}
+
+// syncBuffer is a buffer safe to write while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// test that appended lines are followed, including across a rotation
+func Test_Follow(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "daemonlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "nav-daemon.log")
+
+	w, err := NewWriter(path, 20, 1)
+	assert.Nil(t, err)
+	defer w.Close()
+
+	w.Write([]byte("before\n"))
+
+	_, size, _ := Tail(path, 0)
+
+	out := &syncBuffer{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		Follow(path, size, out, time.Millisecond, stop)
+		close(done)
+	}()
+
+	w.Write([]byte("after\n"))
+	waitFor(t, func() bool { return out.String() == "after\n" })
+
+	// this write rotates the file
+	w.Write([]byte("rotated line\n"))
+	waitFor(t, func() bool { return strings.HasSuffix(out.String(), "rotated line\n") })
+
+	close(stop)
+	<-done
+
+	assert.Equal(t, "after\nrotated line\n", out.String())
+
+}
+
+// waitFor waits for the condition to become true
+func waitFor(t *testing.T, condition func() bool) {
+
+	for i := 0; i < 500; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	t.Fatal("condition not met")
+
+}
@@ -24,6 +24,17 @@ type RPCResponse struct {
 	Message string `json:"message"`
 }
 
+// RPCError is the error object returned by the daemon for a failed call
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error formats the daemon's error code and message
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("daemon error %d: %s", e.Code, e.Message)
+}
+
 // RequestDaemon requests the data via the daemon's RPC api
 // allows auto switches between the testnet and live depending on the config
 func RequestDaemon(coinData conf.CoinData, rpcReqData RPCRequestData, daemonConf conf.DaemonConfig) (*http.Response, error) {
@@ -52,6 +63,47 @@ func RequestDaemon(coinData conf.CoinData, rpcReqData RPCRequestData, daemonConf
 
 }
 
+// CallDaemon requests the method via the daemon's RPC api and decodes the result
+// into result, returning the daemon's own error if the call was rejected
+func CallDaemon(coinData conf.CoinData, method string, params []interface{}, result interface{}) error {
+
+	rpcReqData := RPCRequestData{Method: method, Params: params}
+
+	if params == nil {
+		rpcReqData.Params = []interface{}{}
+	}
+
+	resp, err := RequestDaemon(coinData, rpcReqData, conf.DaemonConf)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	rpcResp := struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&rpcResp)
+
+	if err != nil {
+		return err
+	}
+
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(rpcResp.Result, result)
+
+}
+
 // RPCFailed handles errors encountered when requesting daemon
 func RPCFailed(err error, w http.ResponseWriter) {
 
@@ -0,0 +1,140 @@
+package daemonzmq
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonevents"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockRawTx is the raw hex of txid c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929
+const mockRawTx = "01000000f0f33457011a31aaff8df853d23339e6bd7c3f5c982cd96c5a8616c19a2bdaa8431a07a711010000006a47304402202fbb2c5955013fc4806420a66e5c9116902c0263fe7920ae104ff1818ef62efd022040857e3108ae8f30e8a0800f8f892c8a97aa88b67b8e40032e2ba33d3445230e012103f6c3b8154a19327783dd46e0dda13f812f57b00f9246387f62d5ece8bed767b4ffffffff0300000000000000000000debdfcc1c60100232103f6c3b8154a19327783dd46e0dda13f812f57b00f9246387f62d5ece8bed767b4ac3688d6fcc1c60100232103f6c3b8154a19327783dd46e0dda13f812f57b00f9246387f62d5ece8bed767b4ac00000000"
+
+const mockTxID = "c8dad515d5e5c7a45bc5b3814fcf5e1f63474c9b67f84ee2ab9803f809e94929"
+
+// test the txid of a raw transaction, with and without witness data
+func Test_TxID(t *testing.T) {
+
+	raw, _ := hex.DecodeString(mockRawTx)
+
+	txID, err := TxID(raw)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockTxID, txID)
+
+	// add a marker, flags and a single item witness before nLockTime
+	witness := append([]byte{}, raw[:8]...)
+	witness = append(witness, 0x00, 0x01)
+	witness = append(witness, raw[8:len(raw)-4]...)
+	witness = append(witness, 0x01, 0x02, 0xab, 0xcd)
+	witness = append(witness, raw[len(raw)-4:]...)
+
+	txID, err = TxID(witness)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockTxID, txID)
+
+	_, err = TxID(raw[:50])
+
+	assert.Equal(t, errShortTx, err)
+
+}
+
+// test that malformed notifications are rejected
+func Test_decodeNotification(t *testing.T) {
+
+	seq := []byte{1, 0, 0, 0}
+
+	_, err := decodeNotification("NAV", [][]byte{[]byte(TopicHashBlock), {1, 2}, seq})
+
+	assert.NotNil(t, err)
+
+	_, err = decodeNotification("NAV", [][]byte{[]byte("hashtx"), make([]byte, 32), seq})
+
+	assert.NotNil(t, err)
+
+	_, err = decodeNotification("NAV", [][]byte{[]byte(TopicHashBlock)})
+
+	assert.NotNil(t, err)
+
+}
+
+// mockPublisher stands in for the daemon's ZMQ PUB socket
+func mockPublisher(t *testing.T, l net.Listener, messages [][][]byte) {
+
+	conn, err := l.Accept()
+
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	assert.Nil(t, handshake(conn, "PUB"))
+
+	// wait for both subscriptions before publishing
+	for i := 0; i < 2; i++ {
+		f, err := readFrame(conn)
+		assert.Nil(t, err)
+		assert.Equal(t, byte(1), f.body[0])
+	}
+
+	for _, parts := range messages {
+		for i, part := range parts {
+			writeFrame(conn, frame{more: i < len(parts)-1, body: part})
+		}
+	}
+
+	// hold the connection open until the subscriber is stopped
+	readFrame(conn)
+
+}
+
+// test that published notifications reach the event stream
+func Test_Subscriber(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	assert.Nil(t, err)
+	defer l.Close()
+
+	blockHash := make([]byte, 32)
+	blockHash[0] = 0xab
+	raw, _ := hex.DecodeString(mockRawTx)
+
+	go mockPublisher(t, l, [][][]byte{
+		{[]byte(TopicHashBlock), blockHash, {0, 0, 0, 0}},
+		{[]byte(TopicRawTx), raw, {7, 0, 0, 0}},
+	})
+
+	stream := daemonevents.NewStream()
+	events := stream.Subscribe()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	sub := &Subscriber{Currency: "NAV", Endpoint: "tcp://" + l.Addr().String(), Stream: stream}
+
+	go sub.Run(stop)
+
+	select {
+	case ev := <-events:
+		assert.Equal(t, daemonevents.EventBlock, ev.Type)
+		assert.Equal(t, "NAV", ev.Currency)
+		assert.Equal(t, hex.EncodeToString(blockHash), ev.Hash)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for block event")
+	}
+
+	select {
+	case ev := <-events:
+		assert.Equal(t, daemonevents.EventTx, ev.Type)
+		assert.Equal(t, mockTxID, ev.Hash)
+		assert.Equal(t, uint32(7), ev.Sequence)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for tx event")
+	}
+
+}
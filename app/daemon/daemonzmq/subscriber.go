@@ -0,0 +1,156 @@
+package daemonzmq
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonevents"
+)
+
+// Topics the daemon publishes notifications on
+const (
+	TopicHashBlock = "hashblock"
+	TopicRawTx     = "rawtx"
+)
+
+// hashLength is the length of a block hash
+const hashLength = 32
+
+// dialTimeout is how long to wait for the daemon's socket to accept
+var dialTimeout = 5 * time.Second
+
+// retryDelay is how long to wait before reconnecting to the daemon
+var retryDelay = 2 * time.Second
+
+// Subscriber consumes a daemon's ZMQ notifications and
+// publishes them to the event stream as daemon events
+type Subscriber struct {
+	Currency string
+	Endpoint string
+	Stream   *daemonevents.Stream
+}
+
+// Run connects to the endpoint, reconnecting whenever the daemon
+// goes away, until stop is closed
+func (sub *Subscriber) Run(stop <-chan struct{}) {
+
+	log.Println("Subscribing to " + sub.Currency + " daemon notifications at " + sub.Endpoint)
+
+	for {
+
+		err := sub.consume(stop)
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		log.Println("Lost " + sub.Currency + " daemon notifications, retrying: " + err.Error())
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(retryDelay):
+		}
+
+	}
+
+}
+
+// consume subscribes to the notification topics and publishes
+// events until the connection fails or stop is closed
+func (sub *Subscriber) consume(stop <-chan struct{}) error {
+
+	conn, err := net.DialTimeout("tcp", strings.TrimPrefix(sub.Endpoint, "tcp://"), dialTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	// close the connection on stop so the blocking read returns
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-stop:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := handshake(conn, "SUB"); err != nil {
+		return err
+	}
+
+	// ZMTP 3.0 subscriptions are a message of 0x01 followed by the topic
+	for _, topic := range []string{TopicHashBlock, TopicRawTx} {
+		if err := writeFrame(conn, frame{body: append([]byte{1}, topic...)}); err != nil {
+			return err
+		}
+	}
+
+	for {
+
+		parts, err := readMessage(conn)
+
+		if err != nil {
+			return err
+		}
+
+		ev, err := decodeNotification(sub.Currency, parts)
+
+		if err != nil {
+			log.Println("Skipping " + sub.Currency + " daemon notification: " + err.Error())
+			continue
+		}
+
+		sub.Stream.Publish(ev)
+
+	}
+
+}
+
+// decodeNotification turns a [topic, body, sequence] notification into an event
+func decodeNotification(currency string, parts [][]byte) (daemonevents.Event, error) {
+
+	if len(parts) != 3 || len(parts[2]) != 4 {
+		return daemonevents.Event{}, fmt.Errorf("expected 3 part notification, got %d parts", len(parts))
+	}
+
+	ev := daemonevents.Event{Currency: currency, Sequence: binary.LittleEndian.Uint32(parts[2])}
+
+	switch string(parts[0]) {
+
+	case TopicHashBlock:
+		if len(parts[1]) != hashLength {
+			return daemonevents.Event{}, fmt.Errorf("invalid block hash length %d", len(parts[1]))
+		}
+		ev.Type = daemonevents.EventBlock
+		ev.Hash = hex.EncodeToString(parts[1])
+
+	case TopicRawTx:
+		txID, err := TxID(parts[1])
+		if err != nil {
+			return daemonevents.Event{}, err
+		}
+		ev.Type = daemonevents.EventTx
+		ev.Hash = txID
+		ev.Raw = parts[1]
+
+	default:
+		return daemonevents.Event{}, fmt.Errorf("unknown topic %q", parts[0])
+
+	}
+
+	return ev, nil
+
+}
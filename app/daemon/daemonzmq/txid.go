@@ -0,0 +1,166 @@
+package daemonzmq
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+// errShortTx is returned when a raw transaction ends unexpectedly
+var errShortTx = errors.New("raw transaction is truncated")
+
+// txReader walks the fields of a serialised transaction
+type txReader struct {
+	b   []byte
+	pos int
+}
+
+// skip advances past n bytes
+func (r *txReader) skip(n int) error {
+	if n < 0 || r.pos+n > len(r.b) {
+		return errShortTx
+	}
+	r.pos += n
+	return nil
+}
+
+// varInt reads a bitcoin compact size integer
+func (r *txReader) varInt() (int, error) {
+
+	if r.pos >= len(r.b) {
+		return 0, errShortTx
+	}
+
+	prefix := r.b[r.pos]
+	r.pos++
+
+	size := 0
+
+	switch prefix {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return int(prefix), nil
+	}
+
+	if r.pos+size > len(r.b) {
+		return 0, errShortTx
+	}
+
+	buf := make([]byte, 8)
+	copy(buf, r.b[r.pos:r.pos+size])
+	r.pos += size
+
+	n := binary.LittleEndian.Uint64(buf)
+
+	if n > uint64(len(r.b)) {
+		return 0, errShortTx
+	}
+
+	return int(n), nil
+
+}
+
+// skipVarBytes advances past a length prefixed byte string
+func (r *txReader) skipVarBytes() error {
+	n, err := r.varInt()
+	if err != nil {
+		return err
+	}
+	return r.skip(n)
+}
+
+// TxID returns the txid of a raw NavCoin transaction - the reversed double
+// sha256 of its serialisation without any segregated witness data
+// NavCoin serialises nVersion, nTime, [marker, flags], vin, vout,
+// [witnesses], nLockTime and, from version 2, strDZeel
+func TxID(raw []byte) (string, error) {
+
+	r := &txReader{b: raw}
+
+	if err := r.skip(8); err != nil {
+		return "", err
+	}
+
+	hasWitness := len(raw) > 10 && raw[8] == 0 && raw[9] != 0
+
+	stripped := append([]byte{}, raw[:8]...)
+
+	if hasWitness {
+		r.skip(2)
+	}
+
+	ioStart := r.pos
+
+	vinCount, err := r.varInt()
+
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < vinCount; i++ {
+		if err := r.skip(36); err != nil {
+			return "", err
+		}
+		if err := r.skipVarBytes(); err != nil {
+			return "", err
+		}
+		if err := r.skip(4); err != nil {
+			return "", err
+		}
+	}
+
+	voutCount, err := r.varInt()
+
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < voutCount; i++ {
+		if err := r.skip(8); err != nil {
+			return "", err
+		}
+		if err := r.skipVarBytes(); err != nil {
+			return "", err
+		}
+	}
+
+	stripped = append(stripped, raw[ioStart:r.pos]...)
+
+	if hasWitness {
+		for i := 0; i < vinCount; i++ {
+			items, err := r.varInt()
+			if err != nil {
+				return "", err
+			}
+			for j := 0; j < items; j++ {
+				if err := r.skipVarBytes(); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
+	// nLockTime and any strDZeel make up the rest of the transaction
+	if r.pos+4 > len(raw) {
+		return "", errShortTx
+	}
+
+	stripped = append(stripped, raw[r.pos:]...)
+
+	first := sha256.Sum256(stripped)
+	second := sha256.Sum256(first[:])
+
+	// txids are displayed in reverse byte order
+	for i, j := 0, len(second)-1; i < j; i, j = i+1, j-1 {
+		second[i], second[j] = second[j], second[i]
+	}
+
+	return hex.EncodeToString(second[:]), nil
+
+}
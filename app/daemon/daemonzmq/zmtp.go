@@ -0,0 +1,231 @@
+package daemonzmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A minimal ZMTP 3.0 implementation - just enough of the wire protocol
+// to subscribe to the daemon's PUB socket with the NULL mechanism
+// without requiring libzmq (releases are built with CGO disabled)
+// see https://rfc.zeromq.org/spec/23/
+
+const (
+	greetingLength = 64
+	mechanismNull  = "NULL"
+
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	// maxFrameSize guards against a bad peer asking us to allocate huge frames
+	maxFrameSize = 32 * 1024 * 1024
+)
+
+// errBadGreeting is returned when the peer does not speak ZMTP 3
+var errBadGreeting = errors.New("zmq peer sent an invalid greeting")
+
+// frame is a single ZMTP message or command frame
+type frame struct {
+	more    bool
+	command bool
+	body    []byte
+}
+
+// greeting builds our ZMTP 3.0 greeting with the NULL mechanism
+func greeting() []byte {
+
+	g := make([]byte, greetingLength)
+
+	g[0] = 0xff
+	g[9] = 0x7f
+	g[10] = 3 // major version
+	g[11] = 0 // minor version
+	copy(g[12:32], mechanismNull)
+
+	return g
+
+}
+
+// readGreeting reads and checks the peer's greeting
+func readGreeting(r io.Reader) error {
+
+	g := make([]byte, greetingLength)
+
+	if _, err := io.ReadFull(r, g); err != nil {
+		return err
+	}
+
+	if g[0] != 0xff || g[9]&0x01 != 0x01 || g[10] < 3 {
+		return errBadGreeting
+	}
+
+	if string(bytes.TrimRight(g[12:32], "\x00")) != mechanismNull {
+		return fmt.Errorf("zmq peer requires unsupported mechanism %q", bytes.TrimRight(g[12:32], "\x00"))
+	}
+
+	return nil
+
+}
+
+// readyCommand builds the READY command announcing our socket type
+func readyCommand(socketType string) []byte {
+
+	name := "READY"
+	prop := "Socket-Type"
+
+	b := []byte{byte(len(name))}
+	b = append(b, name...)
+	b = append(b, byte(len(prop)))
+	b = append(b, prop...)
+
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(socketType)))
+	b = append(b, size...)
+
+	return append(b, socketType...)
+
+}
+
+// commandName returns the name of a command frame's body
+func commandName(body []byte) string {
+	if len(body) == 0 || int(body[0]) >= len(body) {
+		return ""
+	}
+	return string(body[1 : 1+int(body[0])])
+}
+
+// writeFrame writes a single frame to w
+func writeFrame(w io.Writer, f frame) error {
+
+	flags := byte(0)
+
+	if f.more {
+		flags |= flagMore
+	}
+
+	if f.command {
+		flags |= flagCommand
+	}
+
+	header := []byte{flags}
+
+	if len(f.body) > 255 {
+		header[0] |= flagLong
+		size := make([]byte, 8)
+		binary.BigEndian.PutUint64(size, uint64(len(f.body)))
+		header = append(header, size...)
+	} else {
+		header = append(header, byte(len(f.body)))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(f.body)
+
+	return err
+
+}
+
+// readFrame reads a single frame from r
+func readFrame(r io.Reader) (frame, error) {
+
+	flags := make([]byte, 1)
+
+	if _, err := io.ReadFull(r, flags); err != nil {
+		return frame{}, err
+	}
+
+	var size uint64
+
+	if flags[0]&flagLong != 0 {
+		b := make([]byte, 8)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return frame{}, err
+		}
+		size = binary.BigEndian.Uint64(b)
+	} else {
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return frame{}, err
+		}
+		size = uint64(b[0])
+	}
+
+	if size > maxFrameSize {
+		return frame{}, fmt.Errorf("zmq frame of %d bytes is too large", size)
+	}
+
+	body := make([]byte, size)
+
+	if _, err := io.ReadFull(r, body); err != nil {
+		return frame{}, err
+	}
+
+	return frame{more: flags[0]&flagMore != 0, command: flags[0]&flagCommand != 0, body: body}, nil
+
+}
+
+// readMessage reads the frames of the next multipart message, skipping commands
+func readMessage(r io.Reader) ([][]byte, error) {
+
+	parts := [][]byte{}
+
+	for {
+
+		f, err := readFrame(r)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if f.command {
+			if commandName(f.body) == "ERROR" {
+				return nil, errors.New("zmq peer sent an error command")
+			}
+			continue
+		}
+
+		parts = append(parts, f.body)
+
+		if !f.more {
+			return parts, nil
+		}
+
+	}
+
+}
+
+// handshake exchanges greetings and READY commands over the connection
+func handshake(rw io.ReadWriter, socketType string) error {
+
+	if _, err := rw.Write(greeting()); err != nil {
+		return err
+	}
+
+	if err := readGreeting(rw); err != nil {
+		return err
+	}
+
+	if err := writeFrame(rw, frame{command: true, body: readyCommand(socketType)}); err != nil {
+		return err
+	}
+
+	f, err := readFrame(rw)
+
+	if err != nil {
+		return err
+	}
+
+	if !f.command || commandName(f.body) != "READY" {
+		return errors.New("zmq peer did not send READY")
+	}
+
+	return nil
+
+}
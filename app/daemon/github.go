@@ -0,0 +1,333 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+)
+
+// maxRateLimitWait is the longest a request waits for GitHub's rate limit to reset before giving up
+var maxRateLimitWait = time.Minute
+
+// defaultRateLimitBackoff is how long GitHub is left alone when it limits us without saying until when
+var defaultRateLimitBackoff = time.Minute
+
+// gitHubClient makes the GitHub API requests, replaced in tests
+var gitHubClient = &http.Client{Timeout: 30 * time.Second}
+
+// rateLimitSleep waits out a short rate limit, replaced in tests
+var rateLimitSleep = time.Sleep
+
+// gitHubCacheDir returns where release metadata is cached, replaced in tests
+var gitHubCacheDir = func() (string, error) {
+
+	path, err := fs.GetCurrentPath()
+
+	if err != nil {
+		return "", err
+	}
+
+	return path + "/data/github", nil
+
+}
+
+// rateLimitedUntil is when GitHub's rate limit resets, no requests are made before then
+var rateLimitedUntil time.Time
+var rateLimitMu sync.Mutex
+
+// RateLimitError is returned when GitHub's rate limit is exhausted and nothing is cached
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return "GitHub API rate limit exceeded until " + e.Reset.Format(time.RFC3339)
+}
+
+// cachedResponse is a GitHub response saved to disk for revalidation and offline use
+type cachedResponse struct {
+	URL       string          `json:"url"`
+	ETag      string          `json:"etag"`
+	Body      json.RawMessage `json:"body"`
+	FetchedAt time.Time       `json:"fetchedAt"`
+}
+
+// gitHubGet decodes the GitHub API response for the url into v. Responses are
+// cached on disk and revalidated with their ETag, the cache is used when GitHub
+// can't be reached or is rate limiting us
+func gitHubGet(url string, v interface{}) error {
+
+	cached := readGitHubCache(url)
+
+	var body []byte
+	var err error
+
+	if reset := rateLimitReset(); time.Now().Before(reset) {
+		err = &RateLimitError{Reset: reset}
+	} else {
+		body, err = fetchGitHub(url, cached, true)
+	}
+
+	if err != nil {
+
+		if cached == nil {
+			return err
+		}
+
+		log.Println("Using release data cached " + cached.FetchedAt.Format(time.RFC3339) + " for " + url + ": " + err.Error())
+		body = cached.Body
+
+	}
+
+	return json.Unmarshal(body, v)
+
+}
+
+// fetchGitHub requests the url, revalidating the cached response, and returns the current body
+func fetchGitHub(url string, cached *cachedResponse, retry bool) ([]byte, error) {
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	if gitHubToken := conf.CurrentServerConfig().GitHubToken; gitHubToken != "" {
+		req.Header.Set("Authorization", "token "+gitHubToken)
+	}
+
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	response, err := gitHubClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	switch {
+
+	case response.StatusCode == http.StatusOK:
+
+		body, err := ioutil.ReadAll(response.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !json.Valid(body) {
+			return nil, fmt.Errorf("GitHub returned invalid JSON for %s", url)
+		}
+
+		writeGitHubCache(cachedResponse{URL: url, ETag: response.Header.Get("ETag"), Body: body, FetchedAt: time.Now()})
+
+		return body, nil
+
+	case response.StatusCode == http.StatusNotModified && cached != nil:
+
+		cached.FetchedAt = time.Now()
+		writeGitHubCache(*cached)
+
+		return cached.Body, nil
+
+	case isRateLimited(response):
+
+		reset := limitUntil(response)
+
+		if wait := time.Until(reset); retry && wait <= maxRateLimitWait {
+			log.Printf("GitHub API rate limited, retrying in %v\n", wait)
+			rateLimitSleep(wait)
+			return fetchGitHub(url, cached, false)
+		}
+
+		return nil, &RateLimitError{Reset: reset}
+
+	}
+
+	return nil, fmt.Errorf("GitHub request for %s failed: %s", url, response.Status)
+
+}
+
+// isRateLimited reports whether GitHub refused the request for exceeding a rate limit
+func isRateLimited(response *http.Response) bool {
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	return response.StatusCode == http.StatusForbidden &&
+		(response.Header.Get("X-RateLimit-Remaining") == "0" || response.Header.Get("Retry-After") != "")
+
+}
+
+// limitUntil records when the response says the rate limit resets and returns it
+func limitUntil(response *http.Response) time.Time {
+
+	reset := time.Now().Add(defaultRateLimitBackoff)
+
+	if seconds, err := strconv.ParseInt(response.Header.Get("Retry-After"), 10, 64); err == nil {
+		reset = time.Now().Add(time.Duration(seconds) * time.Second)
+	} else if epoch, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(epoch, 0)
+	}
+
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+
+	rateLimitedUntil = reset
+
+	return reset
+
+}
+
+// rateLimitReset returns when the last rate limit resets
+func rateLimitReset() time.Time {
+
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+
+	return rateLimitedUntil
+
+}
+
+// gitHubCachePath returns the cache file for the url
+func gitHubCachePath(url string) (string, error) {
+
+	dir, err := gitHubCacheDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(url))
+
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+
+}
+
+// readGitHubCache returns the url's cached response, nil if there is none
+func readGitHubCache(url string) *cachedResponse {
+
+	path, err := gitHubCachePath(url)
+
+	if err != nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil
+	}
+
+	cached := &cachedResponse{}
+
+	if err := json.Unmarshal(data, cached); err != nil || cached.URL != url {
+		return nil
+	}
+
+	return cached
+
+}
+
+// writeGitHubCache saves the response, a failure only costs the next revalidation
+func writeGitHubCache(cached cachedResponse) {
+
+	path, err := gitHubCachePath(cached.URL)
+
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	}
+
+	var data []byte
+
+	if err == nil {
+		data, err = json.Marshal(cached)
+	}
+
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = ioutil.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+
+	if err != nil {
+		log.Println("Failed to cache release data for " + cached.URL + ": " + err.Error())
+	}
+
+}
+
+// gitHubSource reads releases from the coin's GitHub ReleaseAPI and LatestReleaseAPI
+type gitHubSource struct {
+	coinData conf.CoinData
+}
+
+func (s *gitHubSource) Releases() ([]SourceRelease, error) {
+
+	published, err := gitHubReleaseInfo(s.coinData.CurrencyCode, s.coinData.ReleaseAPI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	releases := []SourceRelease{}
+
+	for _, elem := range published {
+		if !elem.Draft {
+			releases = append(releases, elem.sourceRelease())
+		}
+	}
+
+	sortReleases(releases)
+
+	return releases, nil
+
+}
+
+func (s *gitHubSource) Latest() (SourceRelease, error) {
+
+	release := GitHubReleaseData{}
+
+	if err := gitHubGet(s.coinData.LatestReleaseAPI, &release); err != nil {
+		return SourceRelease{}, err
+	}
+
+	return release.sourceRelease(), nil
+
+}
+
+// sourceRelease converts the GitHub release
+func (release GitHubReleaseData) sourceRelease() SourceRelease {
+
+	converted := SourceRelease{
+		Tag:         release.TagName,
+		Name:        release.Name,
+		PublishedAt: release.PublishedAt,
+		Prerelease:  release.Prerelease,
+	}
+
+	for _, asset := range release.Assets {
+		converted.Assets = append(converted.Assets, ReleaseAsset{Name: asset.Name, URL: asset.BrowserDownloadURL})
+	}
+
+	return converted
+
+}
@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockGitHubCache caches release data in a temp dir and clears any
+// rate limit, it returns a func restoring both
+func mockGitHubCache(t *testing.T) func() {
+
+	dir, err := ioutil.TempDir("", "github")
+	assert.Nil(t, err)
+
+	cacheDir := gitHubCacheDir
+
+	gitHubCacheDir = func() (string, error) { return dir, nil }
+	rateLimitedUntil = time.Time{}
+
+	return func() {
+		gitHubCacheDir = cacheDir
+		rateLimitedUntil = time.Time{}
+		os.RemoveAll(dir)
+	}
+
+}
+
+// test that responses are revalidated with their ETag and used offline
+func Test_gitHubGet_cache(t *testing.T) {
+
+	defer mockGitHubCache(t)()
+
+	serverConf := conf.CurrentServerConfig()
+	defer conf.SetServerConfig(serverConf)
+
+	withToken := serverConf
+	withToken.GitHubToken = "secret"
+	conf.SetServerConfig(withToken)
+
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		requests++
+
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`[{"tag_name": "4.3.0"}]`))
+
+	}))
+
+	for i := 0; i < 2; i++ {
+		releases := GitHubReleases{}
+		assert.Nil(t, gitHubGet(server.URL+"/releases", &releases))
+		assert.Equal(t, "4.3.0", releases[0].TagName)
+	}
+
+	assert.Equal(t, 2, requests)
+
+	// GitHub can't be reached so the cached releases are used
+	server.Close()
+
+	releases := GitHubReleases{}
+	assert.Nil(t, gitHubGet(server.URL+"/releases", &releases))
+	assert.Equal(t, "4.3.0", releases[0].TagName)
+
+	// nothing was cached for this url
+	assert.NotNil(t, gitHubGet(server.URL+"/latest", &GitHubReleaseData{}))
+
+}
+
+// test that a response other than 200 is an error
+func Test_gitHubGet_notFound(t *testing.T) {
+
+	defer mockGitHubCache(t)()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	err := gitHubGet(server.URL+"/releases", &GitHubReleases{})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "404")
+
+}
+
+// test that a rate limit is waited out when short and respected when long
+func Test_gitHubGet_rateLimit(t *testing.T) {
+
+	defer mockGitHubCache(t)()
+
+	sleep := rateLimitSleep
+	defer func() { rateLimitSleep = sleep }()
+
+	slept := time.Duration(0)
+	rateLimitSleep = func(d time.Duration) { slept += d }
+
+	requests := 0
+	reset := time.Now().Add(time.Hour).Unix()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		requests++
+
+		switch requests {
+		case 1:
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusForbidden)
+		case 3:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.Write([]byte(`{"tag_name": "4.3.0"}`))
+		}
+
+	}))
+	defer server.Close()
+
+	// the short limit is waited out and the request retried
+	rateLimitedUntil = time.Time{}
+	release := GitHubReleaseData{}
+
+	assert.Nil(t, gitHubGet(server.URL+"/latest", &release))
+	assert.Equal(t, "4.3.0", release.TagName)
+	assert.Equal(t, 5*time.Second, slept.Round(time.Second))
+
+	rateLimitedUntil = time.Time{}
+
+	// the long limit falls back to the cache, or fails without one
+	assert.Nil(t, gitHubGet(server.URL+"/latest", &release))
+
+	err := gitHubGet(server.URL+"/releases", &GitHubReleases{})
+
+	assert.IsType(t, &RateLimitError{}, err)
+	assert.Equal(t, reset, err.(*RateLimitError).Reset.Unix())
+
+	// nothing is requested until the limit resets
+	assert.Equal(t, 3, requests)
+
+}
@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+)
+
+// confHeader opens the managed conf file
+const confHeader = "# Managed by kauri-api and rewritten on every daemon start.\n" +
+	"# Set daemonOptions for the coin in app-config.json instead of editing this file.\n"
+
+// writeDaemonConf renders the coin's DaemonOptions into the managed conf file in dataDir and returns its path
+func writeDaemonConf(coinData conf.CoinData, dataDir string) (string, error) {
+
+	contents, err := renderDaemonConf(coinData.DaemonOptions)
+
+	if err != nil {
+		return "", fmt.Errorf("%s daemonOptions: %v", coinData.CurrencyCode, err)
+	}
+
+	path := filepath.Join(dataDir, coinData.LibPath+".conf")
+
+	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, []byte(contents), 0600); err != nil {
+		return "", err
+	}
+
+	return path, os.Rename(tmp, path)
+
+}
+
+// renderDaemonConf renders the options as conf file lines in name order
+func renderDaemonConf(options map[string]interface{}) (string, error) {
+
+	names := []string{}
+
+	for name := range options {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	contents := confHeader
+
+	for _, name := range names {
+
+		lines, err := conf.DaemonOptionLines(name, options[name])
+
+		if err != nil {
+			return "", err
+		}
+
+		for _, line := range lines {
+			contents += line + "\n"
+		}
+
+	}
+
+	return contents, nil
+
+}
@@ -0,0 +1,41 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+// test that options from the app config are written to the managed conf file
+func Test_writeDaemonConf(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "options")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	options := map[string]interface{}{}
+	json.Unmarshal([]byte(`{"dbcache": 450, "staking": false, "listen": true, "addnode": ["10.0.0.1", "10.0.0.2:44440"]}`), &options)
+
+	path, err := writeDaemonConf(conf.CoinData{CurrencyCode: "NAV", LibPath: "navcoin", DaemonOptions: options}, dir)
+
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "navcoin.conf"), path)
+
+	contents, _ := ioutil.ReadFile(path)
+
+	assert.Equal(t, confHeader+"addnode=10.0.0.1\naddnode=10.0.0.2:44440\ndbcache=450\nlisten=1\nstaking=0\n", string(contents))
+
+	// no options still manages the file
+	_, err = writeDaemonConf(conf.CoinData{CurrencyCode: "NAV", LibPath: "navcoin"}, dir)
+
+	assert.Nil(t, err)
+
+	contents, _ = ioutil.ReadFile(path)
+	assert.Equal(t, confHeader, string(contents))
+
+}
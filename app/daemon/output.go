@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonlog"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+)
+
+// Defaults for the daemon output logs when the server config leaves them unset
+const (
+	DefaultDaemonLogMaxSize = 10
+	DefaultDaemonLogKeep    = 5
+)
+
+// outputs holds each coin's daemon output log, kept open across restarts
+var outputs = map[string]*daemonlog.Writer{}
+var outputsMu sync.Mutex
+
+// OutputLogPath returns the file the coin's daemon stdout and stderr are captured in
+func OutputLogPath(coinData conf.CoinData) (string, error) {
+
+	path, err := fs.GetCurrentPath()
+
+	if err != nil {
+		return "", err
+	}
+
+	return path + "/logs/" + strings.ToLower(coinData.CurrencyCode) + "-daemon.log", nil
+
+}
+
+// DebugLogPath returns the daemon's own debug.log in the coin's DataDir,
+// testnet daemons write it in their network's sub directory
+func DebugLogPath(coinData conf.CoinData) (string, error) {
+
+	path, err := fs.GetCurrentPath()
+
+	if err != nil {
+		return "", err
+	}
+
+	dataDir := path + coinData.DataDir
+
+	if coinData.UseTestNet {
+		if matches, _ := filepath.Glob(filepath.Join(dataDir, "testnet*", "debug.log")); len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+
+	return filepath.Join(dataDir, "debug.log"), nil
+
+}
+
+// daemonOutput returns the log the coin's daemon output is written to
+func daemonOutput(coinData conf.CoinData) (io.Writer, error) {
+
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+
+	if w, ok := outputs[coinData.CurrencyCode]; ok {
+		return w, nil
+	}
+
+	path, err := OutputLogPath(coinData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	serverConf := conf.CurrentServerConfig()
+
+	maxSize := serverConf.DaemonLogMaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultDaemonLogMaxSize
+	}
+
+	keep := serverConf.DaemonLogKeep
+	if keep <= 0 {
+		keep = DefaultDaemonLogKeep
+	}
+
+	w, err := daemonlog.NewWriter(path, int64(maxSize)*1024*1024, keep)
+
+	if err != nil {
+		return nil, err
+	}
+
+	outputs[coinData.CurrencyCode] = w
+
+	return w, nil
+
+}
@@ -0,0 +1,165 @@
+package daemon
+
+import (
+	"errors"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+)
+
+// ErrNotRunning is returned when restarting or stopping a coin whose daemon was not started by the manager
+var ErrNotRunning = errors.New("daemon is not running")
+
+// process is a daemon started by the manager, exited is closed once it exits
+type process struct {
+	cmd    *exec.Cmd
+	path   string
+	exited chan struct{}
+}
+
+// processes holds the running daemon of each currency
+var processes = map[string]*process{}
+var processesMu sync.Mutex
+
+// register records the started daemon for the currency and waits for it to exit
+func register(coinData conf.CoinData, cmd *exec.Cmd, daemonPath string) *process {
+
+	proc := &process{cmd: cmd, path: daemonPath, exited: make(chan struct{})}
+
+	processesMu.Lock()
+	processes[coinData.CurrencyCode] = proc
+	processesMu.Unlock()
+
+	go func() {
+		cmd.Wait()
+		log.Println(coinData.CurrencyCode + " daemon exited")
+		close(proc.exited)
+	}()
+
+	return proc
+
+}
+
+// IsRunning reports whether the currency's daemon is running
+func IsRunning(currency string) bool {
+
+	proc, ok := running(currency)
+
+	if !ok {
+		return false
+	}
+
+	select {
+	case <-proc.exited:
+		return false
+	default:
+		return true
+	}
+
+}
+
+// Restart waits up to timeout for the coin's daemon to shut itself down,
+// as it does after encrypting its wallet, kills it if it hasn't and starts it again
+func Restart(coinData conf.CoinData, timeout time.Duration) error {
+
+	proc, ok := running(coinData.CurrencyCode)
+
+	if !ok {
+		return ErrNotRunning
+	}
+
+	if err := waitForExit(coinData, proc, timeout); err != nil {
+		return err
+	}
+
+	log.Println("Restarting " + coinData.CurrencyCode + " daemon")
+
+	_, err := launchDaemon(coinData, proc.path)
+
+	return err
+
+}
+
+// Stop asks the coin's daemon to shut down and waits up to timeout for it to exit, killing it if it hasn't
+func Stop(coinData conf.CoinData, timeout time.Duration) error {
+
+	proc, ok := running(coinData.CurrencyCode)
+
+	if !ok || !IsRunning(coinData.CurrencyCode) {
+		return ErrNotRunning
+	}
+
+	log.Println("Stopping " + coinData.CurrencyCode + " daemon")
+
+	if err := daemonrpc.CallDaemon(coinData, "stop", nil, nil); err != nil {
+		log.Println("Failed to stop the " + coinData.CurrencyCode + " daemon over RPC, killing it: " + err.Error())
+		timeout = 0
+	}
+
+	return waitForExit(coinData, proc, timeout)
+
+}
+
+// Start starts the coin's daemon again after it was stopped, from the path it last ran from
+func Start(coinData conf.CoinData) error {
+
+	if IsRunning(coinData.CurrencyCode) {
+		return nil
+	}
+
+	proc, ok := running(coinData.CurrencyCode)
+
+	daemonPath := ""
+
+	if ok {
+		daemonPath = proc.path
+	} else {
+		path, err := CheckForDaemon(coinData)
+		if err != nil {
+			return err
+		}
+		daemonPath = path
+	}
+
+	_, err := launchDaemon(coinData, daemonPath)
+
+	return err
+
+}
+
+// running returns the last daemon started for the currency
+func running(currency string) (*process, bool) {
+
+	processesMu.Lock()
+	defer processesMu.Unlock()
+
+	proc, ok := processes[currency]
+
+	return proc, ok
+
+}
+
+// waitForExit waits up to timeout for the daemon to exit, killing it if it hasn't
+func waitForExit(coinData conf.CoinData, proc *process, timeout time.Duration) error {
+
+	select {
+	case <-proc.exited:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	log.Println(coinData.CurrencyCode + " daemon did not exit, killing it")
+
+	if err := proc.cmd.Process.Kill(); err != nil {
+		return err
+	}
+
+	<-proc.exited
+
+	return nil
+
+}
@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+)
+
+// Release channels an unpinned coin's upgrades follow
+const (
+	ChannelStable     = "stable"
+	ChannelPrerelease = "prerelease"
+)
+
+// ErrUnknownChannel is returned when a coin is set to follow a channel that doesn't exist
+var ErrUnknownChannel = errors.New("channel must be " + ChannelStable + " or " + ChannelPrerelease)
+
+// Release is a published daemon release and its state on this node
+type Release struct {
+	Tag         string    `json:"tag"`
+	Name        string    `json:"name"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Prerelease  bool      `json:"prerelease"`
+	HasAsset    bool      `json:"hasAsset"`
+	Installed   bool      `json:"installed"`
+	Current     bool      `json:"current"`
+}
+
+// Pin is the release a coin's upgrades are held to, or the channel they follow
+// Upgrade is the upgrade started when a pin moves the coin off its running version
+type Pin struct {
+	Version string         `json:"version"`
+	Channel string         `json:"channel"`
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+}
+
+// GetReleases lists the releases published by the coin's release source
+func GetReleases(coinData conf.CoinData) ([]Release, error) {
+
+	coinData = activeCoin(coinData)
+
+	published, err := sourceReleases(coinData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	releases := []Release{}
+
+	for _, elem := range published {
+
+		release := Release{
+			Tag:         elem.Tag,
+			Name:        elem.Name,
+			PublishedAt: elem.PublishedAt,
+			Prerelease:  elem.Prerelease,
+			Current:     elem.Tag == coinData.DaemonVersion,
+		}
+
+		dlPath, _, _ := getDownloadPathAndName(coinData, elem)
+		release.HasAsset = dlPath != ""
+		release.Installed = isInstalled(coinData, elem.Tag)
+
+		releases = append(releases, release)
+
+	}
+
+	return releases, nil
+
+}
+
+// GetPin returns the coin's pinned version and channel
+func GetPin(coinData conf.CoinData) Pin {
+
+	coinData = activeCoin(coinData)
+
+	return Pin{Version: coinData.DaemonPin, Channel: channel(coinData)}
+
+}
+
+// SetPin holds the coin's upgrades to the pin's version, or to its channel when
+// the version is empty, and saves it to the app config
+// pinning a version other than the running one starts the upgrade to it
+func SetPin(coinData conf.CoinData, pin Pin) (Pin, error) {
+
+	coinData = activeCoin(coinData)
+	pin.Upgrade = nil
+
+	if pin.Channel == "" {
+		pin.Channel = ChannelStable
+	}
+
+	if pin.Channel != ChannelStable && pin.Channel != ChannelPrerelease {
+		return Pin{}, ErrUnknownChannel
+	}
+
+	if pin.Version != "" && !isInstalled(coinData, pin.Version) {
+
+		releases, err := sourceReleases(coinData)
+
+		if err != nil {
+			return Pin{}, err
+		}
+
+		if _, err := releaseForTag(releases, pin.Version); err != nil {
+			return Pin{}, err
+		}
+
+	}
+
+	err := conf.UpdateCoin(coinData.CurrencyCode, func(c *conf.CoinData) {
+		c.DaemonPin = pin.Version
+		c.DaemonChannel = pin.Channel
+	})
+
+	active, ok := conf.ActiveCoin(coinData.CurrencyCode)
+
+	if ok && pin.Version != "" && pin.Version != active.DaemonVersion && active.DaemonPath == "" {
+
+		status, upgradeErr := StartUpgrade(active, DefaultHealthTimeout)
+
+		if upgradeErr != nil {
+			log.Println("Failed to start the " + active.CurrencyCode + " daemon upgrade to its pin v" + pin.Version + ": " + upgradeErr.Error())
+		} else {
+			pin.Upgrade = &status
+		}
+
+	}
+
+	return pin, err
+
+}
+
+// fetchTargetRelease returns the release the coin should run: its pinned
+// version, or the newest release on its channel
+func fetchTargetRelease(coinData conf.CoinData) (SourceRelease, error) {
+
+	source, err := releaseSource(coinData)
+
+	if err != nil {
+		return SourceRelease{}, err
+	}
+
+	if coinData.DaemonPin == "" && channel(coinData) == ChannelStable {
+		return source.Latest()
+	}
+
+	releases, err := source.Releases()
+
+	if err != nil {
+		return SourceRelease{}, err
+	}
+
+	if coinData.DaemonPin != "" {
+		return releaseForTag(releases, coinData.DaemonPin)
+	}
+
+	return newestRelease(releases, true), nil
+
+}
+
+// sourceReleases lists the releases published by the coin's release source
+func sourceReleases(coinData conf.CoinData) ([]SourceRelease, error) {
+
+	source, err := releaseSource(coinData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return source.Releases()
+
+}
+
+// releaseForTag finds the published release with the tag
+func releaseForTag(releases []SourceRelease, tag string) (SourceRelease, error) {
+
+	for _, elem := range releases {
+		if elem.Tag == tag {
+			return elem, nil
+		}
+	}
+
+	return SourceRelease{}, fmt.Errorf("v%s is not a published release", tag)
+
+}
+
+// newestRelease returns the first release, sources list them newest first,
+// prereleases are only included if asked
+func newestRelease(releases []SourceRelease, prerelease bool) SourceRelease {
+
+	for _, elem := range releases {
+		if prerelease || !elem.Prerelease {
+			return elem
+		}
+	}
+
+	return SourceRelease{}
+
+}
+
+// channel returns the coin's release channel, stable unless set
+func channel(coinData conf.CoinData) string {
+
+	if coinData.DaemonChannel == "" {
+		return ChannelStable
+	}
+
+	return coinData.DaemonChannel
+
+}
+
+// isInstalled reports whether the version of the coin's daemon is in the lib dir
+func isInstalled(coinData conf.CoinData, version string) bool {
+
+	coinData.DaemonVersion = version
+
+	path, err := daemonPath(coinData)
+
+	return err == nil && fs.Exists(path)
+
+}
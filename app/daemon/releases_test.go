@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockSource is a release source with fixed releases
+type mockSource struct {
+	releases []SourceRelease
+}
+
+func (s *mockSource) Releases() ([]SourceRelease, error) {
+	return s.releases, nil
+}
+
+func (s *mockSource) Latest() (SourceRelease, error) {
+	return newestRelease(s.releases, false), nil
+}
+
+// mockReleases replaces every coin's release source with the 4.3.0
+// prerelease and 4.2.1, and returns a func restoring it
+func mockReleases() func() {
+
+	source := releaseSource
+
+	releaseSource = func(coinData conf.CoinData) (ReleaseSource, error) {
+		return &mockSource{releases: []SourceRelease{
+			{Tag: "4.3.0", Prerelease: true, PublishedAt: time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)},
+			{Tag: "4.2.1", PublishedAt: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)},
+		}}, nil
+	}
+
+	return func() { releaseSource = source }
+
+}
+
+// test that the releases are listed and the running version is marked
+func Test_GetReleases(t *testing.T) {
+
+	defer mockReleases()()
+
+	releases, err := GetReleases(conf.CoinData{CurrencyCode: "NAVREL", DaemonVersion: "4.2.1"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(releases))
+
+	assert.Equal(t, "4.3.0", releases[0].Tag)
+	assert.True(t, releases[0].Prerelease)
+	assert.False(t, releases[0].Current)
+
+	assert.Equal(t, "4.2.1", releases[1].Tag)
+	assert.True(t, releases[1].Current)
+	assert.False(t, releases[1].Installed)
+
+}
+
+// test that upgrades target the pinned version, or the newest on the channel
+func Test_fetchTargetRelease(t *testing.T) {
+
+	defer mockReleases()()
+
+	release, err := fetchTargetRelease(conf.CoinData{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.1", release.Tag)
+
+	release, err = fetchTargetRelease(conf.CoinData{DaemonChannel: ChannelPrerelease})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.3.0", release.Tag)
+
+	release, err = fetchTargetRelease(conf.CoinData{DaemonPin: "4.2.1", DaemonChannel: ChannelPrerelease})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.1", release.Tag)
+
+	_, err = fetchTargetRelease(conf.CoinData{DaemonPin: "4.4.0"})
+	assert.NotNil(t, err)
+
+}
+
+// test that only published versions and known channels can be pinned
+func Test_SetPin(t *testing.T) {
+
+	defer mockReleases()()
+
+	conf.AppConf.Coins = []conf.CoinData{{CurrencyCode: "NAVPIN", DaemonVersion: "4.2.1"}}
+	defer func() { conf.AppConf.Coins = nil }()
+
+	coinData := conf.CoinData{CurrencyCode: "NAVPIN"}
+
+	_, err := SetPin(coinData, Pin{Channel: "nightly"})
+	assert.Equal(t, ErrUnknownChannel, err)
+
+	_, err = SetPin(coinData, Pin{Version: "4.4.0"})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, Pin{Channel: ChannelStable}, GetPin(coinData))
+
+	launched := []string{}
+	defer mockUpgrade("4.3.0", nil, &launched)()
+
+	mockProcess("NAVPIN", "/lib/navcoin-4.2.1/bin/navcoind")
+
+	// the app config was not loaded from a file so the pin is applied but not saved
+	pin, err := SetPin(coinData, Pin{Version: "4.3.0"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, Pin{Version: "4.3.0", Channel: ChannelStable}, GetPin(coinData))
+
+	// pinning another version than the running one upgrades to it
+	if assert.NotNil(t, pin.Upgrade) {
+		assert.Equal(t, "4.2.1", pin.Upgrade.FromVersion)
+	}
+
+	assert.Equal(t, UpgradeDone, waitForUpgrade(t, "NAVPIN").State)
+	assert.Equal(t, []string{"/lib/navcoin-4.3.0/bin/navcoind"}, launched)
+
+	// the pinned version is now running
+	pin, _ = SetPin(coinData, Pin{Version: "4.3.0"})
+
+	assert.Nil(t, pin.Upgrade)
+
+}
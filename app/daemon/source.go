@@ -0,0 +1,327 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+)
+
+// Release sources a coin's daemon can be installed from
+const (
+	SourceGitHub   = "github"
+	SourceManifest = "manifest"
+	SourceLocal    = "local"
+)
+
+// ErrNoReleaseURL is returned when a manifest or local source has nowhere to read from
+var ErrNoReleaseURL = errors.New("the release source needs a releaseUrl")
+
+// releaseSource picks the coin's release source, replaced in tests
+var releaseSource = newReleaseSource
+
+// ReleaseSource lists the daemon releases a coin can be installed from
+type ReleaseSource interface {
+	// Releases lists the published releases, newest first
+	Releases() ([]SourceRelease, error)
+	// Latest returns the newest stable release
+	Latest() (SourceRelease, error)
+}
+
+// SourceRelease is a daemon release as any source describes it
+type SourceRelease struct {
+	Tag         string         `json:"tag"`
+	Name        string         `json:"name"`
+	PublishedAt time.Time      `json:"publishedAt"`
+	Prerelease  bool           `json:"prerelease"`
+	Assets      []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a downloadable archive of a release, the URL may be file://
+type ReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// newReleaseSource returns the source set by the coin's ReleaseSource, GitHub by default
+func newReleaseSource(coinData conf.CoinData) (ReleaseSource, error) {
+
+	switch coinData.ReleaseSource {
+
+	case "", SourceGitHub:
+		return &gitHubSource{coinData: coinData}, nil
+
+	case SourceManifest:
+		if coinData.ReleaseURL == "" {
+			return nil, ErrNoReleaseURL
+		}
+		return &manifestSource{url: coinData.ReleaseURL}, nil
+
+	case SourceLocal:
+		if coinData.ReleaseURL == "" {
+			return nil, ErrNoReleaseURL
+		}
+		dir := coinData.ReleaseURL
+		if strings.HasPrefix(dir, "file:") {
+			parsed, err := url.Parse(dir)
+			if err != nil {
+				return nil, err
+			}
+			dir = fs.FilePath(parsed)
+		}
+		return &localSource{dir: dir}, nil
+
+	}
+
+	return nil, fmt.Errorf("unknown release source %q", coinData.ReleaseSource)
+
+}
+
+// manifestSource reads releases from a JSON manifest, asset urls
+// are resolved relative to the manifest's
+type manifestSource struct {
+	url string
+}
+
+// releaseManifest is the manifest's schema
+type releaseManifest struct {
+	Releases []SourceRelease `json:"releases"`
+}
+
+func (s *manifestSource) Releases() ([]SourceRelease, error) {
+
+	body, _, err := fs.Fetch(s.url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	manifest := releaseManifest{}
+
+	if err := json.NewDecoder(body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("release manifest %s: %v", s.url, err)
+	}
+
+	base, err := url.Parse(s.url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range manifest.Releases {
+		for j, asset := range manifest.Releases[i].Assets {
+
+			ref, err := url.Parse(asset.URL)
+
+			if err != nil {
+				return nil, fmt.Errorf("release manifest %s: %v", s.url, err)
+			}
+
+			manifest.Releases[i].Assets[j].URL = base.ResolveReference(ref).String()
+
+		}
+	}
+
+	sortReleases(manifest.Releases)
+
+	return manifest.Releases, nil
+
+}
+
+func (s *manifestSource) Latest() (SourceRelease, error) {
+	return latestStable(s)
+}
+
+// localSource reads releases from a directory holding a sub directory of assets per release tag
+// releases are ordered by comparing their tags as versions, a tag with a suffix, e.g. 4.3.0-rc1,
+// is a prerelease, and an optional release.json in a tag's directory describes it instead
+type localSource struct {
+	dir string
+}
+
+// localReleaseFile describes a local release, it is not one of its assets
+const localReleaseFile = "release.json"
+
+// localRelease is the optional description of a local release
+type localRelease struct {
+	Name        string    `json:"name"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Prerelease  *bool     `json:"prerelease"`
+}
+
+func (s *localSource) Releases() ([]SourceRelease, error) {
+
+	entries, err := ioutil.ReadDir(s.dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	releases := []SourceRelease{}
+
+	for _, entry := range entries {
+
+		if !entry.IsDir() {
+			continue
+		}
+
+		release, err := s.describe(entry.Name())
+
+		if err != nil {
+			return nil, err
+		}
+
+		files, err := ioutil.ReadDir(filepath.Join(s.dir, entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if !file.IsDir() && file.Name() != localReleaseFile {
+				assetURL, err := fs.FileURL(filepath.Join(s.dir, entry.Name(), file.Name()))
+				if err != nil {
+					return nil, err
+				}
+				release.Assets = append(release.Assets, ReleaseAsset{Name: file.Name(), URL: assetURL})
+			}
+		}
+
+		releases = append(releases, release)
+
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		return compareVersions(releases[i].Tag, releases[j].Tag) > 0
+	})
+
+	return releases, nil
+
+}
+
+// describe returns the tag's release from its release.json, or from the tag if it has none
+func (s *localSource) describe(tag string) (SourceRelease, error) {
+
+	release := SourceRelease{Tag: tag, Name: tag, Prerelease: strings.Contains(tag, "-")}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, tag, localReleaseFile))
+
+	if os.IsNotExist(err) {
+		return release, nil
+	}
+
+	if err != nil {
+		return SourceRelease{}, err
+	}
+
+	described := localRelease{}
+
+	if err := json.Unmarshal(data, &described); err != nil {
+		return SourceRelease{}, fmt.Errorf("invalid %s for %s: %v", localReleaseFile, tag, err)
+	}
+
+	if described.Name != "" {
+		release.Name = described.Name
+	}
+
+	if described.Prerelease != nil {
+		release.Prerelease = *described.Prerelease
+	}
+
+	release.PublishedAt = described.PublishedAt
+
+	return release, nil
+
+}
+
+func (s *localSource) Latest() (SourceRelease, error) {
+	return latestStable(s)
+}
+
+// latestStable returns the source's newest release that isn't a prerelease
+func latestStable(source ReleaseSource) (SourceRelease, error) {
+
+	releases, err := source.Releases()
+
+	if err != nil {
+		return SourceRelease{}, err
+	}
+
+	return newestRelease(releases, false), nil
+
+}
+
+// compareVersions compares two version tags part by part, e.g. 4.10.0 is newer than 4.9.1,
+// and a release is newer than its prereleases, e.g. 4.3.0 is newer than 4.3.0-rc1
+func compareVersions(a string, b string) int {
+
+	split := func(tag string) ([]string, string) {
+		parts := strings.SplitN(strings.TrimPrefix(tag, "v"), "-", 2)
+		suffix := ""
+		if len(parts) == 2 {
+			suffix = parts[1]
+		}
+		return strings.Split(parts[0], "."), suffix
+	}
+
+	aParts, aSuffix := split(a)
+	bParts, bSuffix := split(b)
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+
+		aPart, bPart := "0", "0"
+
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		aNum, aErr := strconv.Atoi(aPart)
+		bNum, bErr := strconv.Atoi(bPart)
+
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum > bNum {
+				return 1
+			}
+			return -1
+		case (aErr != nil || bErr != nil) && aPart != bPart:
+			return strings.Compare(aPart, bPart)
+		}
+
+	}
+
+	switch {
+	case aSuffix == bSuffix:
+		return 0
+	case aSuffix == "":
+		return 1
+	case bSuffix == "":
+		return -1
+	}
+
+	return strings.Compare(aSuffix, bSuffix)
+
+}
+
+// sortReleases orders the releases newest first
+func sortReleases(releases []SourceRelease) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].PublishedAt.After(releases[j].PublishedAt)
+	})
+}
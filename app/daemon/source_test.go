@@ -0,0 +1,169 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+// test that each coin gets the release source its config selects
+func Test_newReleaseSource(t *testing.T) {
+
+	source, err := newReleaseSource(conf.CoinData{})
+	assert.Nil(t, err)
+	assert.IsType(t, &gitHubSource{}, source)
+
+	_, err = newReleaseSource(conf.CoinData{ReleaseSource: SourceManifest})
+	assert.Equal(t, ErrNoReleaseURL, err)
+
+	source, err = newReleaseSource(conf.CoinData{ReleaseSource: SourceLocal, ReleaseURL: "file:///opt/navcoin"})
+	assert.Nil(t, err)
+	assert.Equal(t, &localSource{dir: "/opt/navcoin"}, source)
+
+	_, err = newReleaseSource(conf.CoinData{ReleaseSource: "ftp"})
+	assert.NotNil(t, err)
+
+}
+
+// test that GitHub drafts are left out and assets converted
+func Test_gitHubSource(t *testing.T) {
+
+	defer mockGitHubCache(t)()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"tag_name": "4.4.0", "draft": true},
+			{"tag_name": "4.3.0", "assets": [{"name": "navcoin-4.3.0-osx64.tar.gz", "browser_download_url": "https://example.com/navcoin-4.3.0-osx64.tar.gz"}]}
+		]`))
+	}))
+	defer server.Close()
+
+	source := &gitHubSource{coinData: conf.CoinData{CurrencyCode: "NAV", ReleaseAPI: server.URL + "/releases"}}
+
+	releases, err := source.Releases()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(releases))
+	assert.Equal(t, "4.3.0", releases[0].Tag)
+	assert.Equal(t, []ReleaseAsset{{Name: "navcoin-4.3.0-osx64.tar.gz", URL: "https://example.com/navcoin-4.3.0-osx64.tar.gz"}}, releases[0].Assets)
+
+}
+
+// test that a manifest's relative asset urls resolve beside it
+func Test_manifestSource(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "manifest")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "releases.json"), []byte(`{"releases": [
+		{"tag": "4.2.1", "publishedAt": "2018-06-01T00:00:00Z", "assets": [{"name": "navcoin-4.2.1-osx64.tar.gz", "url": "4.2.1/navcoin-4.2.1-osx64.tar.gz"}]},
+		{"tag": "4.3.0", "publishedAt": "2018-07-01T00:00:00Z", "prerelease": true}
+	]}`), 0644)
+
+	source := &manifestSource{url: "file://" + filepath.Join(dir, "releases.json")}
+
+	releases, err := source.Releases()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.3.0", releases[0].Tag)
+	assert.Equal(t, "file://"+filepath.Join(dir, "4.2.1", "navcoin-4.2.1-osx64.tar.gz"), releases[1].Assets[0].URL)
+
+	latest, err := source.Latest()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.1", latest.Tag)
+
+}
+
+// test that a local directory lists a release per sub directory, newest version first
+func Test_localSource(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "local")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, tag := range []string{"4.2.1", "4.10.0-rc1", "4.9.0", "4.3.0"} {
+		os.MkdirAll(filepath.Join(dir, tag), 0755)
+		ioutil.WriteFile(filepath.Join(dir, tag, "navcoin-"+tag+"-osx64.tar.gz"), []byte("daemon"), 0644)
+	}
+
+	// copying an old release into the mirror doesn't make it the newest
+	os.Chtimes(filepath.Join(dir, "4.2.1"), time.Now(), time.Now().Add(time.Hour))
+
+	// a release.json describes the release
+	ioutil.WriteFile(filepath.Join(dir, "4.9.0", localReleaseFile), []byte(`{"name": "Beta", "prerelease": true}`), 0644)
+
+	source := &localSource{dir: dir}
+	releases, err := source.Releases()
+
+	assert.Nil(t, err)
+
+	tags := []string{}
+	for _, release := range releases {
+		tags = append(tags, release.Tag)
+	}
+
+	assert.Equal(t, []string{"4.10.0-rc1", "4.9.0", "4.3.0", "4.2.1"}, tags)
+	assert.True(t, releases[0].Prerelease, "a tag with a suffix is a prerelease")
+	assert.True(t, releases[1].Prerelease)
+	assert.Equal(t, "Beta", releases[1].Name)
+	assert.Equal(t, 1, len(releases[1].Assets), "the release.json is not an asset")
+	assert.Equal(t, "file://"+filepath.Join(dir, "4.3.0", "navcoin-4.3.0-osx64.tar.gz"), releases[2].Assets[0].URL)
+
+	latest, err := source.Latest()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.3.0", latest.Tag)
+
+}
+
+// test that a relative releaseUrl lists assets that can be fetched from another working directory
+func Test_localSource_relative(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "local")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "releases", "4.3.0"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "releases", "4.3.0", "navcoin-4.3.0-osx64.tar.gz"), []byte("daemon"), 0644)
+
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	defer os.Chdir(wd)
+
+	assert.Nil(t, os.Chdir(dir))
+
+	source, err := newReleaseSource(conf.CoinData{ReleaseSource: SourceLocal, ReleaseURL: "releases"})
+	assert.Nil(t, err)
+
+	releases, err := source.Releases()
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.Chdir(wd))
+
+	body, _, err := fs.Fetch(releases[0].Assets[0].URL)
+	assert.Nil(t, err)
+	defer body.Close()
+
+	contents, err := ioutil.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "daemon", string(contents))
+
+}
+
+func Test_compareVersions(t *testing.T) {
+	assert.Equal(t, 1, compareVersions("4.10.0", "4.9.1"))
+	assert.Equal(t, 0, compareVersions("v4.2.1", "4.2.1.0"))
+	assert.Equal(t, 1, compareVersions("4.3.0", "4.3.0-rc1"))
+	assert.Equal(t, -1, compareVersions("4.3.0-rc1", "4.3.0-rc2"))
+	assert.Equal(t, -1, compareVersions("4.2.1", "4.3.0-rc1"))
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+)
+
+// ErrSystemDaemon is returned when a system daemon would have to be replaced by kauri-api
+var ErrSystemDaemon = errors.New("the daemon is installed by the system and can't be upgraded here")
+
+// versionPattern finds the version in a daemon's -version output, e.g. "NavCoin Core Daemon version v4.2.1.0-g9f4b3c1"
+var versionPattern = regexp.MustCompile(`v?(\d+(\.\d+)+)`)
+
+// checkSystemDaemon resolves the coin's DaemonPath, a bare name is searched for in PATH,
+// and checks the binary reports the coin's DaemonVersion
+func checkSystemDaemon(coinData conf.CoinData) (string, error) {
+
+	path, err := exec.LookPath(coinData.DaemonPath)
+
+	if err != nil {
+		return "", fmt.Errorf("%s daemon not found at %s: %v", coinData.CurrencyCode, coinData.DaemonPath, err)
+	}
+
+	log.Println("Checking the version of the " + coinData.CurrencyCode + " system daemon at " + path)
+
+	output, err := exec.Command(path, "-version").Output()
+
+	if err != nil {
+		return "", fmt.Errorf("%s daemon at %s did not report its version: %v", coinData.CurrencyCode, path, err)
+	}
+
+	version := reportedVersion(string(output))
+
+	if !sameVersion(version, coinData.DaemonVersion) {
+		return "", fmt.Errorf("%s daemon at %s is v%s, the config expects v%s", coinData.CurrencyCode, path, version, coinData.DaemonVersion)
+	}
+
+	log.Println(coinData.CurrencyCode + " system daemon located for v" + coinData.DaemonVersion)
+
+	return path, nil
+
+}
+
+// reportedVersion reads the version from the first line of a daemon's -version output
+func reportedVersion(output string) string {
+
+	line := strings.SplitN(output, "\n", 2)[0]
+
+	match := versionPattern.FindStringSubmatch(line)
+
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+
+}
+
+// sameVersion compares versions ignoring trailing zero parts, so 4.2.1.0 is 4.2.1
+func sameVersion(a string, b string) bool {
+
+	trim := func(version string) string {
+		version = strings.TrimPrefix(version, "v")
+		for strings.HasSuffix(version, ".0") {
+			version = strings.TrimSuffix(version, ".0")
+		}
+		return version
+	}
+
+	return a != "" && trim(a) == trim(b)
+
+}
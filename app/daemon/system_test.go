@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockSystemDaemon writes a navcoind to dir that reports the version
+func mockSystemDaemon(t *testing.T, dir string, version string) string {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the mock daemon is a shell script")
+	}
+
+	path := filepath.Join(dir, "navcoind")
+	script := "#!/bin/sh\necho 'NavCoin Core Daemon version v" + version + "-g9f4b3c1'\necho 'Copyright (C) 2018'\n"
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(script), 0755))
+
+	return path
+
+}
+
+// test that a daemon at an explicit path or in PATH is used when its version matches
+func Test_CheckForDaemon_system(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "system")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	daemonPath := mockSystemDaemon(t, dir, "4.2.1.0")
+
+	path, err := CheckForDaemon(conf.CoinData{CurrencyCode: "NAV", DaemonVersion: "4.2.1", DaemonPath: daemonPath})
+
+	assert.Nil(t, err)
+	assert.Equal(t, daemonPath, path)
+
+	envPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", envPath)
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+envPath)
+
+	path, err = CheckForDaemon(conf.CoinData{CurrencyCode: "NAV", DaemonVersion: "4.2.1", DaemonPath: "navcoind"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, daemonPath, path)
+
+	// another version isn't used
+	_, err = CheckForDaemon(conf.CoinData{CurrencyCode: "NAV", DaemonVersion: "4.3.0", DaemonPath: "navcoind"})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is v4.2.1.0")
+
+	// nor is a system daemon upgraded
+	_, err = StartUpgrade(conf.CoinData{CurrencyCode: "NAVSYS", DaemonPath: "navcoind"}, time.Second)
+	assert.Equal(t, ErrSystemDaemon, err)
+
+}
+
+// test that versions match with or without trailing zero parts
+func Test_sameVersion(t *testing.T) {
+
+	assert.True(t, sameVersion("4.2.1.0", "4.2.1"))
+	assert.True(t, sameVersion("v4.2.1", "4.2.1"))
+	assert.False(t, sameVersion("4.2.10", "4.2.1"))
+	assert.False(t, sameVersion("", ""))
+
+	assert.Equal(t, "4.2.1.0", reportedVersion("NavCoin Core Daemon version v4.2.1.0-g9f4b3c1\nCopyright (C) 2009-2018"))
+
+}
@@ -0,0 +1,305 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+)
+
+// Upgrade states reported while a daemon is upgraded
+const (
+	UpgradeChecking    = "checking"
+	UpgradeDownloading = "downloading"
+	UpgradeStopping    = "stopping"
+	UpgradeStarting    = "starting"
+	UpgradeVerifying   = "verifying"
+	UpgradeDone        = "done"
+	UpgradeUpToDate    = "up_to_date"
+	UpgradeRolledBack  = "rolled_back"
+	UpgradeFailed      = "failed"
+)
+
+// DefaultHealthTimeout is how long an upgraded daemon has to answer RPC calls before it is rolled back
+const DefaultHealthTimeout = 5 * time.Minute
+
+// stopTimeout is how long a daemon is given to shut down cleanly
+const stopTimeout = 2 * time.Minute
+
+// healthPollInterval is how often an upgraded daemon is checked while it starts
+var healthPollInterval = 2 * time.Second
+
+// ErrUpgradeInProgress is returned when an upgrade is requested while one is running
+var ErrUpgradeInProgress = errors.New("an upgrade is already in progress")
+
+// the upgrade's steps, replaced in tests
+var (
+	targetRelease  = fetchTargetRelease
+	installRelease = downloadRelease
+	launch         = launchDaemon
+	healthCheck    = waitHealthy
+)
+
+// UpgradeStatus is the progress of a coin's daemon upgrade
+type UpgradeStatus struct {
+	Currency    string    `json:"currency"`
+	State       string    `json:"state"`
+	FromVersion string    `json:"fromVersion"`
+	ToVersion   string    `json:"toVersion,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt,omitempty"`
+}
+
+// inProgress reports whether the upgrade is still running
+func (status UpgradeStatus) inProgress() bool {
+	switch status.State {
+	case UpgradeDone, UpgradeUpToDate, UpgradeRolledBack, UpgradeFailed:
+		return false
+	}
+	return true
+}
+
+// upgrades holds the status of each coin's last upgrade
+var upgrades = map[string]*UpgradeStatus{}
+var upgradesMu sync.Mutex
+
+// GetUpgradeStatus returns the status of the currency's last upgrade, false if none was attempted
+func GetUpgradeStatus(currency string) (UpgradeStatus, bool) {
+
+	upgradesMu.Lock()
+	defer upgradesMu.Unlock()
+
+	status, ok := upgrades[currency]
+
+	if !ok {
+		return UpgradeStatus{}, false
+	}
+
+	return *status, true
+
+}
+
+// updateUpgrade applies the update to the currency's status
+func updateUpgrade(currency string, update func(status *UpgradeStatus)) {
+
+	upgradesMu.Lock()
+	defer upgradesMu.Unlock()
+
+	update(upgrades[currency])
+
+}
+
+// StartUpgrade upgrades the coin's daemon to its pinned or newest release in the background,
+// the daemon is rolled back if the new one isn't healthy within healthTimeout
+func StartUpgrade(coinData conf.CoinData, healthTimeout time.Duration) (UpgradeStatus, error) {
+
+	coinData = activeCoin(coinData)
+
+	if coinData.DaemonPath != "" {
+		return UpgradeStatus{}, ErrSystemDaemon
+	}
+
+	upgradesMu.Lock()
+
+	if status, ok := upgrades[coinData.CurrencyCode]; ok && status.inProgress() {
+		upgradesMu.Unlock()
+		return UpgradeStatus{}, ErrUpgradeInProgress
+	}
+
+	status := &UpgradeStatus{
+		Currency:    coinData.CurrencyCode,
+		State:       UpgradeChecking,
+		FromVersion: coinData.DaemonVersion,
+		StartedAt:   time.Now(),
+	}
+
+	upgrades[coinData.CurrencyCode] = status
+
+	started := *status
+
+	upgradesMu.Unlock()
+
+	go func() {
+
+		state, err := upgrade(coinData, healthTimeout)
+
+		updateUpgrade(coinData.CurrencyCode, func(status *UpgradeStatus) {
+			status.State = state
+			status.FinishedAt = time.Now()
+			if err != nil {
+				status.Error = err.Error()
+			}
+		})
+
+	}()
+
+	return started, nil
+
+}
+
+// upgrade runs the upgrade and returns its final state
+func upgrade(coinData conf.CoinData, healthTimeout time.Duration) (string, error) {
+
+	currency := coinData.CurrencyCode
+
+	release, err := targetRelease(coinData)
+
+	if err != nil {
+		return UpgradeFailed, err
+	}
+
+	if release.Tag == "" || release.Tag == coinData.DaemonVersion {
+		return UpgradeUpToDate, nil
+	}
+
+	newCoin := coinData
+	newCoin.DaemonVersion = release.Tag
+
+	log.Println("Upgrading " + currency + " daemon from v" + coinData.DaemonVersion + " to v" + newCoin.DaemonVersion)
+
+	setUpgradeState(currency, UpgradeDownloading, func(status *UpgradeStatus) { status.ToVersion = newCoin.DaemonVersion })
+
+	newPath, err := installRelease(newCoin, release)
+
+	if err != nil {
+		return UpgradeFailed, err
+	}
+
+	oldPath, err := currentPath(coinData)
+
+	if err != nil {
+		return UpgradeFailed, err
+	}
+
+	setUpgradeState(currency, UpgradeStopping, nil)
+
+	if err := Stop(coinData, stopTimeout); err != nil && err != ErrNotRunning {
+		return UpgradeFailed, err
+	}
+
+	setUpgradeState(currency, UpgradeStarting, nil)
+
+	_, err = launch(newCoin, newPath)
+
+	if err == nil {
+		setUpgradeState(currency, UpgradeVerifying, nil)
+		err = healthCheck(newCoin, healthTimeout)
+	}
+
+	if err != nil {
+		return rollback(coinData, oldPath, err)
+	}
+
+	if saveErr := conf.UpdateCoin(currency, func(c *conf.CoinData) { c.DaemonVersion = newCoin.DaemonVersion }); saveErr != nil {
+		log.Println("Failed to save the " + currency + " daemon version, it will start v" + coinData.DaemonVersion + " next run: " + saveErr.Error())
+	}
+
+	log.Println(currency + " daemon upgraded to v" + newCoin.DaemonVersion)
+
+	return UpgradeDone, nil
+
+}
+
+// rollback stops the failed upgrade and starts the previous daemon again
+func rollback(coinData conf.CoinData, oldPath string, cause error) (string, error) {
+
+	log.Println("Upgraded " + coinData.CurrencyCode + " daemon is not healthy, rolling back: " + cause.Error())
+
+	if err := Stop(coinData, stopTimeout); err != nil && err != ErrNotRunning {
+		return UpgradeFailed, fmt.Errorf("%v, and stopping it failed: %v", cause, err)
+	}
+
+	if _, err := launch(coinData, oldPath); err != nil {
+		return UpgradeFailed, fmt.Errorf("%v, and restarting v%s failed: %v", cause, coinData.DaemonVersion, err)
+	}
+
+	return UpgradeRolledBack, cause
+
+}
+
+// setUpgradeState moves the currency's upgrade to the state, applying any further update
+func setUpgradeState(currency string, state string, update func(status *UpgradeStatus)) {
+
+	updateUpgrade(currency, func(status *UpgradeStatus) {
+		status.State = state
+		if update != nil {
+			update(status)
+		}
+	})
+
+}
+
+// activeCoin returns the coin's current config, which an earlier upgrade may have changed
+func activeCoin(coinData conf.CoinData) conf.CoinData {
+
+	if active, ok := conf.ActiveCoin(coinData.CurrencyCode); ok {
+		return active
+	}
+
+	return coinData
+
+}
+
+// currentPath returns the path the coin's daemon is running from
+func currentPath(coinData conf.CoinData) (string, error) {
+
+	if proc, ok := running(coinData.CurrencyCode); ok {
+		return proc.path, nil
+	}
+
+	return CheckForDaemon(coinData)
+
+}
+
+// downloadRelease downloads and extracts the release beside the
+// current version and returns the new daemon's path
+func downloadRelease(newCoin conf.CoinData, release SourceRelease) (string, error) {
+
+	dlPath, dlName, _ := getDownloadPathAndName(newCoin, release)
+
+	if dlPath == "" {
+		return "", fmt.Errorf("v%s has no release for this platform", newCoin.DaemonVersion)
+	}
+
+	if err := fs.DownloadExtract(dlPath, dlName); err != nil {
+		return "", err
+	}
+
+	return CheckForDaemon(newCoin)
+
+}
+
+// waitHealthy waits up to timeout for the daemon to answer RPC calls
+func waitHealthy(coinData conf.CoinData, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+
+		if !IsRunning(coinData.CurrencyCode) {
+			return fmt.Errorf("v%s exited while starting", coinData.DaemonVersion)
+		}
+
+		var height int64
+
+		err := daemonrpc.CallDaemon(coinData, "getblockcount", nil, &height)
+
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("v%s did not answer within %v: %v", coinData.DaemonVersion, timeout, err)
+		}
+
+		time.Sleep(healthPollInterval)
+
+	}
+
+}
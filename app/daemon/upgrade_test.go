@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockUpgrade replaces the upgrade's steps, recording the paths launched,
+// and returns a func restoring them
+func mockUpgrade(tagName string, healthErr error, launched *[]string) func() {
+
+	release, install, start, health := targetRelease, installRelease, launch, healthCheck
+
+	targetRelease = func(coinData conf.CoinData) (SourceRelease, error) {
+		return SourceRelease{Tag: tagName}, nil
+	}
+
+	installRelease = func(newCoin conf.CoinData, release SourceRelease) (string, error) {
+		return "/lib/navcoin-" + newCoin.DaemonVersion + "/bin/navcoind", nil
+	}
+
+	launch = func(coinData conf.CoinData, daemonPath string) (*exec.Cmd, error) {
+		*launched = append(*launched, daemonPath)
+		return nil, nil
+	}
+
+	healthCheck = func(coinData conf.CoinData, timeout time.Duration) error {
+		return healthErr
+	}
+
+	return func() { targetRelease, installRelease, launch, healthCheck = release, install, start, health }
+
+}
+
+// mockProcess registers an exited daemon of the currency that ran from path
+func mockProcess(currency string, path string) {
+
+	processesMu.Lock()
+	defer processesMu.Unlock()
+
+	processes[currency] = &process{path: path, exited: make(chan struct{})}
+	close(processes[currency].exited)
+
+}
+
+// waitForUpgrade waits for the currency's upgrade to finish
+func waitForUpgrade(t *testing.T, currency string) UpgradeStatus {
+
+	for i := 0; i < 100; i++ {
+		if status, _ := GetUpgradeStatus(currency); !status.inProgress() {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("upgrade did not finish")
+
+	return UpgradeStatus{}
+
+}
+
+// test that a healthy upgrade is kept and saved to the config
+func Test_StartUpgrade(t *testing.T) {
+
+	launched := []string{}
+	defer mockUpgrade("4.3.0", nil, &launched)()
+
+	conf.AppConf.Coins = []conf.CoinData{{CurrencyCode: "NAVUP", DaemonVersion: "4.2.1"}}
+	defer func() { conf.AppConf.Coins = nil }()
+
+	mockProcess("NAVUP", "/lib/navcoin-4.2.1/bin/navcoind")
+
+	// the app config was not loaded from a file so it isn't saved
+	status, err := StartUpgrade(conf.CoinData{CurrencyCode: "NAVUP"}, time.Second)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.1", status.FromVersion)
+
+	status = waitForUpgrade(t, "NAVUP")
+
+	assert.Equal(t, UpgradeDone, status.State)
+	assert.Equal(t, "4.3.0", status.ToVersion)
+	assert.Equal(t, []string{"/lib/navcoin-4.3.0/bin/navcoind"}, launched)
+
+	coinData, _ := conf.ActiveCoin("NAVUP")
+	assert.Equal(t, "4.3.0", coinData.DaemonVersion)
+
+	// the latest release is now running
+	_, err = StartUpgrade(coinData, time.Second)
+	assert.Nil(t, err)
+
+	status = waitForUpgrade(t, "NAVUP")
+	assert.Equal(t, UpgradeUpToDate, status.State)
+
+}
+
+// test that an unhealthy upgrade is rolled back to the previous daemon
+func Test_StartUpgrade_rollback(t *testing.T) {
+
+	launched := []string{}
+	defer mockUpgrade("4.3.0", errors.New("did not answer"), &launched)()
+
+	mockProcess("NAVRB", "/lib/navcoin-4.2.1/bin/navcoind")
+
+	_, err := StartUpgrade(conf.CoinData{CurrencyCode: "NAVRB", DaemonVersion: "4.2.1"}, time.Second)
+
+	assert.Nil(t, err)
+
+	status := waitForUpgrade(t, "NAVRB")
+
+	assert.Equal(t, UpgradeRolledBack, status.State)
+	assert.Equal(t, "did not answer", status.Error)
+	assert.Equal(t, []string{"/lib/navcoin-4.3.0/bin/navcoind", "/lib/navcoin-4.2.1/bin/navcoind"}, launched)
+
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -44,19 +45,19 @@ func DownloadExtract(url string, assetName string) error {
 
 	path, err := GetCurrentPath()
 
+	if err != nil {
+		return err
+	}
+
 	downloadLocation := path + "/" + assetName
 
 	extractPath := path + "/lib"
 
-	Download(url, downloadLocation)
-
-	Extract(assetName, downloadLocation, extractPath)
-
-	if err != nil {
+	if err := Download(url, downloadLocation); err != nil {
 		return err
 	}
 
-	return nil
+	return Extract(assetName, downloadLocation, extractPath)
 
 }
 
@@ -79,53 +80,66 @@ func CreateDataDir(dirPath string) {
 	}
 }
 
-// Download performs file download of the given url
-// this method provides no feedback to the system
-func Download(url string, downloadTofileName string) {
+// Download performs file download of the given url, which may be file://
+func Download(url string, downloadTofileName string) error {
 
 	log.Println("Downloading", url)
 	log.Println("Destination", downloadTofileName)
 	log.Println("This could take a few mins :)")
 
-	output, err := os.Create(downloadTofileName)
+	body, _, err := Fetch(url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
 
-	response, err := http.Get(url)
+	output, err := os.Create(downloadTofileName)
 	if err != nil {
-		log.Println("Error while downloading", url, "-", err)
-		return
+		return err
 	}
-	defer response.Body.Close()
+	defer output.Close()
 
-	n, err := io.Copy(output, response.Body)
+	n, err := io.Copy(output, body)
 	if err != nil {
-		log.Println("Error while downloading", url, "-", err)
-		return
+		return err
 	}
 
 	log.Println(n, "bytes downloaded")
 
+	return nil
+
 }
 
 // Extract will call Unzip or Untar depending on the detected file extension
-func Extract(assetName string, downloadLocation string, extractPath string) {
+func Extract(assetName string, downloadLocation string, extractPath string) error {
 
-	log.Println("Extracting " + assetName + " from " + extractPath + " to " + downloadLocation)
+	log.Println("Extracting " + assetName + " from " + downloadLocation + " to " + extractPath)
+
+	var err error
 
 	switch filepath.Ext(assetName) {
 	case ".zip":
-		Unzip(downloadLocation, extractPath)
+		err = Unzip(downloadLocation, extractPath)
 	case ".gz":
-		Untar(downloadLocation, extractPath)
+		err = Untar(downloadLocation, extractPath)
+	default:
+		err = fmt.Errorf("unsupported archive %s", assetName)
+	}
+
+	if err != nil {
+		return err
 	}
 
 	log.Println("File extracted to " + extractPath)
 
+	return nil
+
 }
 
 // Unzip takes a src and destination path and unzips accordingly
 func Unzip(src, dest string) error {
 
-	log.Println("Unzip " + src + " from " + dest)
+	log.Println("Unzip " + src + " to " + dest)
 
 	r, err := zip.OpenReader(src)
 	if err != nil {
@@ -134,43 +148,70 @@ func Unzip(src, dest string) error {
 	defer r.Close()
 
 	for _, f := range r.File {
-		rc, err := f.Open()
+
+		target, err := archiveTarget(dest, f.Name)
 		if err != nil {
 			return err
 		}
-		defer rc.Close()
 
-		fpath := filepath.Join(dest, f.Name)
 		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, f.Mode())
-		} else {
-			var fdir string
-			if lastIndex := strings.LastIndex(fpath, string(os.PathSeparator)); lastIndex > -1 {
-				fdir = fpath[:lastIndex]
-			}
-
-			err = os.MkdirAll(fdir, f.Mode())
-			if err != nil {
-				log.Fatal(err)
+			if err := os.MkdirAll(target, 0755); err != nil {
 				return err
 			}
-			f, err := os.OpenFile(
-				fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer f.Close()
+			continue
+		}
 
-			_, err = io.Copy(f, rc)
-			if err != nil {
-				return err
-			}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
 		}
+
+		if err := unzipFile(f, target); err != nil {
+			return err
+		}
+
 	}
 
 	return nil
 }
 
+// unzipFile copies the zip entry to target, closing both before returning
+// so a large archive doesn't hold every entry open
+func unzipFile(f *zip.File, target string) error {
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+
+	return err
+
+}
+
+// archiveTarget returns where the archive entry is extracted to in dst,
+// refusing entries that would be written outside it
+func archiveTarget(dst string, name string) (string, error) {
+
+	root := filepath.Clean(dst)
+	target := filepath.Join(root, name)
+
+	// the archive's own root, e.g. the ./ entry tar -C dir . writes, is dst itself
+	if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %s is outside %s", name, dst)
+	}
+
+	return target, nil
+
+}
+
 // Untar takes a gzStream and destination path;
 // opens gzStream, creates new reader, checks headers
 // passes on target and header to
@@ -179,12 +220,16 @@ func Untar(gzStream string, dst string) error {
 	log.Println("Untar the " + gzStream + " to " + dst)
 
 	r, err := os.Open(gzStream)
-	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
 
-	defer gzr.Close()
+	gzr, err := gzip.NewReader(r)
 	if err != nil {
 		return err
 	}
+	defer gzr.Close()
 
 	tr := tar.NewReader(gzr)
 
@@ -199,7 +244,10 @@ func Untar(gzStream string, dst string) error {
 			continue
 		}
 
-		target := filepath.Join(dst, header.Name)
+		target, err := archiveTarget(dst, header.Name)
+		if err != nil {
+			return err
+		}
 
 		// check the file type
 		switch header.Typeflag {
@@ -212,16 +260,13 @@ func Untar(gzStream string, dst string) error {
 				}
 			}
 
-			// if it's a file create it
+			// if it's a file create it, and its dir if the archive has no entry for it
 		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
 				return err
 			}
-			defer f.Close()
 
-			// copy over contents
-			if _, err := io.Copy(f, tr); err != nil {
+			if err := untarFile(tr, target, os.FileMode(header.Mode)); err != nil {
 				return err
 			}
 		}
@@ -229,6 +274,94 @@ func Untar(gzStream string, dst string) error {
 	}
 }
 
+// untarFile copies the current tar entry to target
+func untarFile(tr *tar.Reader, target string, mode os.FileMode) error {
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// copy over contents
+	_, err = io.Copy(f, tr)
+
+	return err
+
+}
+
+// FilePath returns the local path of a file:// url, a Windows
+// drive path losing the slash that comes before it in the url
+func FilePath(fileURL *url.URL) string {
+
+	path := fileURL.Path
+
+	if len(path) > 1 && filepath.VolumeName(path[1:]) != "" {
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path)
+
+}
+
+// FileURL returns the file:// url of the path, made absolute first
+// so a relative path or a Windows drive isn't read as the url's host
+func FileURL(path string) (string, error) {
+
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	slashed := filepath.ToSlash(abs)
+
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+
+	return (&url.URL{Scheme: "file", Path: slashed}).String(), nil
+
+}
+
+// Fetch opens the url for reading and returns its size, -1 if unknown
+// file:// urls are read from disk so mirrors can be local
+func Fetch(rawURL string) (io.ReadCloser, int64, error) {
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if parsed.Scheme == "file" {
+		f, err := os.Open(FilePath(parsed))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+
+		return f, info.Size(), nil
+	}
+
+	response, err := http.Get(rawURL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, 0, fmt.Errorf("fetching %s: %s", rawURL, response.Status)
+	}
+
+	return response.Body, response.ContentLength, nil
+
+}
+
 // GetCurrentPath gets the path of the go app
 func GetCurrentPath() (string, error) {
 	ex, err := os.Executable()
@@ -0,0 +1,125 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTarball writes a tar.gz of the entries, a name ending in / is a directory
+func writeTarball(t *testing.T, path string, entries []string) {
+
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+
+	for _, name := range entries {
+		if name[len(name)-1] == '/' {
+			assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}))
+			continue
+		}
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name)), Typeflag: tar.TypeReg}))
+		tw.Write([]byte(name))
+	}
+
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, gzw.Close())
+
+}
+
+// writeZip writes a zip of the entries
+func writeZip(t *testing.T, path string, entries []string) {
+
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	for _, name := range entries {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		w.Write([]byte(name))
+	}
+
+	assert.Nil(t, zw.Close())
+
+}
+
+// test that a snapshot packed with tar -C dir . extracts, and entries outside dst are refused
+func Test_Untar(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "untar")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "snapshot.tar.gz")
+	dst := filepath.Join(dir, "data")
+
+	writeTarball(t, archive, []string{"./", "./blocks/", "./blocks/blk00000.dat", "./chainstate/CURRENT"})
+
+	assert.Nil(t, Untar(archive, dst))
+
+	contents, err := ioutil.ReadFile(filepath.Join(dst, "blocks", "blk00000.dat"))
+	assert.Nil(t, err)
+	assert.Equal(t, "./blocks/blk00000.dat", string(contents))
+	assert.True(t, Exists(filepath.Join(dst, "chainstate", "CURRENT")))
+
+	writeTarball(t, archive, []string{"./", "../escaped"})
+
+	assert.EqualError(t, Untar(archive, dst), "archive entry ../escaped is outside "+dst)
+	assert.False(t, Exists(filepath.Join(dir, "escaped")))
+
+}
+
+// test that zip entries extract and entries outside dst are refused
+func Test_Unzip(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "unzip")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "snapshot.zip")
+	dst := filepath.Join(dir, "data")
+
+	writeZip(t, archive, []string{"blocks/", "blocks/blk00000.dat", "chainstate/CURRENT"})
+
+	assert.Nil(t, Unzip(archive, dst))
+
+	contents, err := ioutil.ReadFile(filepath.Join(dst, "blocks", "blk00000.dat"))
+	assert.Nil(t, err)
+	assert.Equal(t, "blocks/blk00000.dat", string(contents))
+	assert.True(t, Exists(filepath.Join(dst, "chainstate", "CURRENT")))
+
+	writeZip(t, archive, []string{"../escaped"})
+
+	assert.EqualError(t, Unzip(archive, dst), "archive entry ../escaped is outside "+dst)
+	assert.False(t, Exists(filepath.Join(dir, "escaped")))
+
+}
+
+// test that a relative path becomes a file url that reads back as the absolute path
+func Test_FileURL(t *testing.T) {
+
+	fileURL, err := FileURL(filepath.Join("releases", "4.3.0", "navcoin 4.3.0.zip"))
+	assert.Nil(t, err)
+
+	abs, err := filepath.Abs(filepath.Join("releases", "4.3.0", "navcoin 4.3.0.zip"))
+	assert.Nil(t, err)
+
+	parsed, err := url.Parse(fileURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "", parsed.Host)
+	assert.Equal(t, abs, FilePath(parsed))
+
+}
@@ -0,0 +1,37 @@
+// Package rpctest mocks the daemon's RPC api for tests, it is only
+// imported from _test.go files so httpmock stays out of the server
+package rpctest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+// CoinData returns the coin the RPC calls under test are made for
+func CoinData() conf.CoinData {
+	data := conf.CoinData{}
+	data.CurrencyCode = "NAV"
+	return data
+}
+
+// Responder returns the mocked response body for the requested rpc method
+// any {{txid}} placeholder in the body is replaced with the first request param
+func Responder(responses map[string]string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		rpcReq := struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}{}
+		json.NewDecoder(req.Body).Decode(&rpcReq)
+		body := responses[rpcReq.Method]
+		if len(rpcReq.Params) > 0 {
+			body = strings.Replace(body, "{{txid}}", fmt.Sprint(rpcReq.Params[0]), -1)
+		}
+		return httpmock.NewStringResponse(200, body), nil
+	}
+}
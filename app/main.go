@@ -1,17 +1,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 
 	"github.com/Encrypt-S/kauri-api/app/api"
 	"github.com/Encrypt-S/kauri-api/app/conf"
 	"github.com/Encrypt-S/kauri-api/app/manager"
-	"github.com/gorilla/mux"
 )
 
 // Idflags set by GoReleaser
@@ -23,6 +25,13 @@ var (
 
 func main() {
 
+	checkConfig := flag.Bool("check-config", false, "validate the server and app configs, then exit non-zero if either is invalid")
+	flag.Parse()
+
+	if *checkConfig {
+		os.Exit(runConfigCheck())
+	}
+
 	// init app errors
 	api.BuildAppErrors()
 
@@ -45,7 +54,7 @@ func main() {
 	// load the app config - required - contains active coin data
 	err = conf.LoadAppConfig()
 	if err != nil {
-		log.Println("Failed to load the app config: " + err.Error())
+		log.Fatal("Failed to load the app config: " + err.Error())
 	}
 
 	// load the dev config file if one is set
@@ -54,18 +63,92 @@ func main() {
 	// start the daemon managers for active coins
 	manager.StartAllDaemonManagers(conf.AppConf.Coins)
 
-	// setup the router
-	router := mux.NewRouter()
+	// size the transaction caches, loading them from disk if persisted
+	err = manager.StartTxCaches(conf.CurrentServerConfig())
+	if err != nil {
+		log.Println("Failed to load the transaction caches: " + err.Error())
+	}
+
+	// start the local address history stores for active coins
+	err = manager.StartHistoryStores(conf.AppConf.Coins)
+	if err != nil {
+		log.Println("Failed to start the address history stores: " + err.Error())
+	}
 
-	// setup the api meta and coin meta handlers
-	api.InitMetaHandlers(router, "api")
+	// start the websocket subscription hubs for active coins
+	manager.StartHubs(conf.AppConf.Coins)
 
-	// start the transaction handlers for active coins
-	manager.StartWalletHandlers(router, conf.AppConf.Coins)
+	// start the webhook watcher for active coins
+	err = manager.StartWebhooks(conf.AppConf.Coins)
+	if err != nil {
+		log.Println("Failed to start webhooks: " + err.Error())
+	}
+
+	// schedule the wallet backups
+	err = manager.StartBackups(conf.CurrentServerConfig(), conf.AppConf.Coins)
+	if err != nil {
+		log.Println("Failed to start wallet backups: " + err.Error())
+	}
+
+	// setup the router, a config reload swaps in one for the new config
+	handler := manager.NewHandler(manager.NewRouter(conf.AppConf.Coins))
+
+	// reload the configs when their files change
+	err = manager.WatchConfigs(handler, conf.AppConfigPath(), conf.ServerConfigPath())
+	if err != nil {
+		log.Println("Failed to watch the configs, changes need a restart: " + err.Error())
+	}
+
+	// stop the services on shutdown so the persisted caches are saved
+	stopServicesOnSignal()
 
 	// set the proper server port
-	port := fmt.Sprintf(":%d", conf.ServerConf.ManagerAPIPort)
+	port := fmt.Sprintf(":%d", conf.CurrentServerConfig().ManagerAPIPort)
 
 	// start http server and listen up
-	http.ListenAndServe(port, router)
+	http.ListenAndServe(port, handler)
+}
+
+// stopServicesOnSignal stops the background services and exits on an interrupt or terminate signal
+func stopServicesOnSignal() {
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		log.Println(fmt.Sprintf("received %v, stopping services", sig))
+		manager.StopServices()
+		os.Exit(0)
+	}()
+
+}
+
+// runConfigCheck loads and validates the configs, reporting every problem,
+// and returns the process exit code
+func runConfigCheck() int {
+
+	// the server config is only read, loading it would generate an API token
+	serverPath, err := conf.FindServerConfig()
+
+	if err == nil {
+		_, err = conf.ReadServerConfig(serverPath)
+	}
+
+	if err != nil {
+		fmt.Println("server config: " + err.Error())
+		return 1
+	}
+
+	fmt.Println("server config ok: " + serverPath)
+
+	if err := conf.LoadAppConfig(); err != nil {
+		fmt.Println("app config: " + err.Error())
+		return 1
+	}
+
+	fmt.Println("app config ok: " + conf.AppConfigPath())
+
+	return 0
+
 }
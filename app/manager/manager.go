@@ -2,13 +2,136 @@ package manager
 
 import (
 	"log"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/backup"
+	"github.com/Encrypt-S/kauri-api/app/cache"
 	"github.com/Encrypt-S/kauri-api/app/conf"
 	"github.com/Encrypt-S/kauri-api/app/daemon"
 	"github.com/Encrypt-S/kauri-api/app/daemon/daemonapi"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonhistory"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonhub"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+	"github.com/Encrypt-S/kauri-api/app/webhook"
 	"github.com/gorilla/mux"
 )
 
+// coinService tracks a coin's background services, closing stop ends
+// them and done is waited on until they have finished
+type coinService struct {
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// services are the running coin services by currency code
+var services = make(map[string]*coinService)
+var servicesMu sync.Mutex
+
+// serviceFor returns the coin's services, creating them if the coin has none running
+func serviceFor(currency string) *coinService {
+
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+
+	svc, ok := services[currency]
+
+	if !ok {
+		svc = &coinService{stop: make(chan struct{})}
+		services[currency] = svc
+	}
+
+	return svc
+
+}
+
+// stopCoinServices stops the coin's hub and history store and waits for them to finish
+func stopCoinServices(currency string) {
+
+	servicesMu.Lock()
+	svc, ok := services[currency]
+	delete(services, currency)
+	servicesMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	close(svc.stop)
+	svc.done.Wait()
+
+	hubsMu.Lock()
+	delete(hubs, currency)
+	hubsMu.Unlock()
+
+	daemonhistory.Unregister(currency)
+
+}
+
+// cacheSavers periodically save the persisted transaction caches,
+// stopping them saves each cache a last time
+var cacheSavers *coinService
+
+// StopServices stops every coin's services, the webhook watcher, backup
+// schedule and cache savers, waiting for the coin services and last cache saves
+func StopServices() {
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	servicesMu.Lock()
+	currencies := make([]string, 0, len(services))
+	for currency := range services {
+		currencies = append(currencies, currency)
+	}
+	servicesMu.Unlock()
+
+	for _, currency := range currencies {
+		stopCoinServices(currency)
+	}
+
+	close(listStop)
+	listStop = make(chan struct{})
+
+	stopCacheSavers()
+
+}
+
+// stopCacheSavers stops the cache savers and waits for their last saves
+func stopCacheSavers() {
+
+	if cacheSavers == nil {
+		return
+	}
+
+	close(cacheSavers.stop)
+	cacheSavers.done.Wait()
+	cacheSavers = nil
+
+}
+
+// listStop ends the services given the whole coin list, the webhook
+// watcher and backup schedule, so a reload can start them with a new one
+var listStop = make(chan struct{})
+
+// restartListServices stops the webhook watcher and backup schedule and starts them for the coins
+func restartListServices(serverConf conf.ServerConfig, activeCoins []conf.CoinData) {
+
+	close(listStop)
+	listStop = make(chan struct{})
+
+	if err := StartWebhooks(activeCoins); err != nil {
+		log.Println("Failed to start webhooks: " + err.Error())
+	}
+
+	if err := StartBackups(serverConf, activeCoins); err != nil {
+		log.Println("Failed to start wallet backups: " + err.Error())
+	}
+
+}
+
 // StartAllDaemonManagers ranges through coins, starts daemons
 func StartAllDaemonManagers(activeCoins []conf.CoinData) {
 
@@ -20,6 +143,26 @@ func StartAllDaemonManagers(activeCoins []conf.CoinData) {
 
 }
 
+// NewRouter builds the router serving the api for the active coins
+func NewRouter(activeCoins []conf.CoinData) *mux.Router {
+
+	router := mux.NewRouter()
+
+	// setup the api meta and coin meta handlers
+	api.InitMetaHandlers(router, "api")
+
+	// setup the address validation handlers
+	api.InitAddressHandlers(router, "api")
+
+	StartWalletHandlers(router, activeCoins)
+	InitHubHandlers(router, activeCoins)
+	InitWebhookHandlers(router, activeCoins)
+	InitBackupHandlers(router, activeCoins)
+
+	return router
+
+}
+
 // StartWalletHandlers ranges through activeCoins, inits handlers
 func StartWalletHandlers(r *mux.Router, activeCoins []conf.CoinData) {
 
@@ -27,6 +170,251 @@ func StartWalletHandlers(r *mux.Router, activeCoins []conf.CoinData) {
 
 	for _, coinData := range activeCoins {
 		daemonapi.InitWalletHandlers(r, coinData, "api")
+		daemonapi.InitExplorerHandlers(r, coinData, "api")
+		daemonapi.InitStakingHandlers(r, coinData, "api")
+		daemonapi.InitColdStakingHandlers(r, coinData, "api")
+		daemonapi.InitDAOHandlers(r, coinData, "api")
+		daemonapi.InitWalletLockHandlers(r, coinData, "api")
+		daemonapi.InitBootstrapHandlers(r, coinData, "api")
+		daemonapi.InitUpgradeHandlers(r, coinData, "api")
+		daemonapi.InitReleaseHandlers(r, coinData, "api")
+		daemonapi.InitLogHandlers(r, coinData, "api")
+	}
+
+}
+
+// hubs are the running subscription hubs by currency code
+var hubs = make(map[string]*daemonhub.Hub)
+var hubsMu sync.Mutex
+
+// StartHubs ranges through activeCoins and starts a subscription hub for each
+func StartHubs(activeCoins []conf.CoinData) {
+
+	log.Println("ranging through active coins, starting subscription hubs")
+
+	for _, coinData := range activeCoins {
+
+		hub := daemonhub.NewHub(coinData)
+
+		hubsMu.Lock()
+		hubs[coinData.CurrencyCode] = hub
+		hubsMu.Unlock()
+
+		svc := serviceFor(coinData.CurrencyCode)
+		svc.done.Add(1)
+
+		go func() {
+			defer svc.done.Done()
+			hub.Run(svc.stop)
+		}()
+
+	}
+
+}
+
+// InitHubHandlers registers the websocket handler of each active coin's hub
+func InitHubHandlers(r *mux.Router, activeCoins []conf.CoinData) {
+
+	hubsMu.Lock()
+	defer hubsMu.Unlock()
+
+	for _, coinData := range activeCoins {
+		if hub, ok := hubs[coinData.CurrencyCode]; ok {
+			daemonhub.InitHubHandlers(r, hub, "api")
+		}
+	}
+
+}
+
+// webhookFile is the watch list file, relative to the app's path
+const webhookFile = "/data/webhooks.json"
+
+// webhookStore holds the webhook watches, it is loaded once
+var webhookStore *webhook.Store
+
+// StartWebhooks loads the persisted webhook watches, the first
+// time it is called, and starts watching the active coins
+func StartWebhooks(activeCoins []conf.CoinData) error {
+
+	log.Println("loading webhook watches, starting webhook watcher")
+
+	if webhookStore == nil {
+
+		path, err := fs.GetCurrentPath()
+
+		if err != nil {
+			return err
+		}
+
+		store := webhook.NewStore(path + webhookFile)
+
+		if err := store.Load(); err != nil {
+			return err
+		}
+
+		webhookStore = store
+
+	}
+
+	go webhook.NewWatcher(webhookStore, activeCoins).Run(listStop)
+
+	return nil
+
+}
+
+// InitWebhookHandlers registers the webhook handlers once the watches are loaded
+func InitWebhookHandlers(r *mux.Router, activeCoins []conf.CoinData) {
+	if webhookStore != nil {
+		webhook.InitWebhookHandlers(r, webhookStore, activeCoins, "api")
+	}
+}
+
+// backupDir is the default wallet backup dir, relative to the app's path
+const backupDir = "/backups"
+
+// backups makes the wallet backups with the server config's settings
+var backups *backup.Backups
+
+// StartBackups sets up the wallet backups and, if an interval
+// is configured, backs up the active coins on schedule
+func StartBackups(serverConf conf.ServerConfig, activeCoins []conf.CoinData) error {
+
+	dir := serverConf.BackupDir
+
+	if dir == "" {
+		path, err := fs.GetCurrentPath()
+		if err != nil {
+			return err
+		}
+		dir = path + backupDir
+	}
+
+	backups = backup.New(dir, serverConf.BackupKeep, serverConf.BackupPassphrase)
+
+	if serverConf.BackupInterval > 0 {
+		log.Printf("backing up wallets every %d minutes to %s", serverConf.BackupInterval, dir)
+		go backups.Run(activeCoins, time.Duration(serverConf.BackupInterval)*time.Minute, listStop)
+	}
+
+	return nil
+
+}
+
+// InitBackupHandlers registers the wallet backup handlers of the active coins
+func InitBackupHandlers(r *mux.Router, activeCoins []conf.CoinData) {
+
+	if backups == nil {
+		return
+	}
+
+	for _, coinData := range activeCoins {
+		backup.InitBackupHandlers(r, backups, coinData, "api")
+	}
+
+}
+
+// cacheSaveInterval is how often persisted caches are written to disk
+const cacheSaveInterval = 5 * time.Minute
+
+// StartTxCaches sizes the transaction caches and, if configured,
+// loads them from disk and saves them periodically
+func StartTxCaches(serverConf conf.ServerConfig) error {
+
+	caches := daemonapi.InitTxCaches(serverConf)
+
+	if !serverConf.TxCachePersist {
+		return nil
 	}
 
+	log.Println("loading persisted transaction caches")
+
+	path, err := fs.GetCurrentPath()
+
+	if err != nil {
+		return err
+	}
+
+	stopCacheSavers()
+
+	svc := &coinService{stop: make(chan struct{})}
+	cacheSavers = svc
+
+	for _, c := range caches {
+
+		name := c.Name()
+
+		if err := c.Persist(path + "/data/" + name + "-cache.json"); err != nil {
+			return err
+		}
+
+		svc.done.Add(1)
+
+		go func(c *cache.Cache) {
+			defer svc.done.Done()
+			c.SaveEvery(cacheSaveInterval, svc.stop, func(err error) {
+				log.Println("Failed to save " + name + " cache: " + err.Error())
+			})
+		}(c)
+
+	}
+
+	return nil
+
+}
+
+// StartHistoryStores opens the local address history store of each
+// active coin with an address index and keeps it synced with the daemon
+func StartHistoryStores(activeCoins []conf.CoinData) error {
+
+	log.Println("ranging through active coins, starting address history stores")
+
+	path, err := fs.GetCurrentPath()
+
+	if err != nil {
+		return err
+	}
+
+	fs.CreateDataDir("/data")
+
+	for _, coinData := range activeCoins {
+
+		if !coinData.IndexTransactions {
+			continue
+		}
+
+		store, err := daemonhistory.Open(coinData, historyPath(path, coinData))
+
+		if err != nil {
+			return err
+		}
+
+		daemonhistory.Register(store)
+
+		svc := serviceFor(coinData.CurrencyCode)
+		svc.done.Add(1)
+
+		go func() {
+			defer svc.done.Done()
+			store.Run(svc.stop)
+			store.Close()
+		}()
+
+	}
+
+	return nil
+
+}
+
+// historyPath returns the coin's history store file under the app path, testnet
+// coins get their own file so their history isn't mixed with the live chain's
+func historyPath(path string, coinData conf.CoinData) string {
+
+	name := strings.ToLower(coinData.CurrencyCode)
+
+	if coinData.UseTestNet {
+		name += "-testnet"
+	}
+
+	return path + "/data/" + name + "-history.db"
+
 }
@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonapi"
+	"github.com/Encrypt-S/kauri-api/app/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+// test that stopping the services saves the persisted caches a last time
+func Test_StopServices_savesCaches(t *testing.T) {
+
+	assert.Nil(t, StartTxCaches(conf.ServerConfig{TxCachePersist: true}))
+
+	path, err := fs.GetCurrentPath()
+	assert.Nil(t, err)
+
+	c := daemonapi.InitTxCaches(conf.ServerConfig{})[0]
+	file := filepath.Join(path, "data", c.Name()+"-cache.json")
+	defer os.Remove(file)
+
+	assert.Nil(t, c.Set("NAV:txid", "tx"))
+	assert.False(t, fs.Exists(file), "the cache isn't saved before the interval")
+
+	StopServices()
+
+	assert.True(t, fs.Exists(file), "the cache is saved when the services stop")
+	assert.Nil(t, cacheSavers)
+
+}
+
+// test that testnet coins keep their history in their own store
+func Test_historyPath(t *testing.T) {
+
+	coinData := conf.CoinData{CurrencyCode: "NAV"}
+
+	assert.Equal(t, "/app/data/nav-history.db", historyPath("/app", coinData))
+
+	coinData.UseTestNet = true
+
+	assert.Equal(t, "/app/data/nav-testnet-history.db", historyPath("/app", coinData))
+
+}
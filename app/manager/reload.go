@@ -0,0 +1,390 @@
+package manager
+
+import (
+	"log"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon"
+	"github.com/fsnotify/fsnotify"
+)
+
+// daemonStopTimeout is how long a reload waits for a removed or relaunched daemon to exit
+const daemonStopTimeout = 2 * time.Minute
+
+// reloadDelay lets an editor finish writing a config before it is reloaded
+var reloadDelay = 500 * time.Millisecond
+
+// stopDaemon and startDaemon stop and start a coin's daemon on reload, replaced in tests
+var (
+	stopDaemon  = daemon.Stop
+	startDaemon = daemon.StartManager
+)
+
+// reloadMu serialises reloads
+var reloadMu sync.Mutex
+
+// daemonOps chains each currency's daemon stops and starts, so a daemon is
+// never started while an earlier reload is still stopping it
+var daemonOps = struct {
+	sync.Mutex
+	last map[string]chan struct{}
+}{last: make(map[string]chan struct{})}
+
+// queueDaemonOp runs op once the currency's earlier ops have finished
+func queueDaemonOp(currency string, op func()) {
+
+	daemonOps.Lock()
+	prev := daemonOps.last[currency]
+	done := make(chan struct{})
+	daemonOps.last[currency] = done
+	daemonOps.Unlock()
+
+	go func() {
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		op()
+	}()
+
+}
+
+// Handler serves the current router, a reload swaps in one built for the new config
+type Handler struct {
+	mu     sync.RWMutex
+	router http.Handler
+}
+
+// NewHandler returns a Handler serving the router
+func NewHandler(router http.Handler) *Handler {
+	return &Handler{router: router}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	h.mu.RLock()
+	router := h.router
+	h.mu.RUnlock()
+
+	router.ServeHTTP(w, r)
+
+}
+
+// Swap serves the router from the next request on
+func (h *Handler) Swap(router http.Handler) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.router = router
+
+}
+
+// CoinChange is a coin whose settings differ in the new app config,
+// Relaunch is set when its daemon must restart to pick them up
+type CoinChange struct {
+	From     conf.CoinData
+	To       conf.CoinData
+	Relaunch bool
+}
+
+// CoinDiff is how the coins of a new app config differ from the running ones
+type CoinDiff struct {
+	Added   []conf.CoinData
+	Removed []conf.CoinData
+	Changed []CoinChange
+}
+
+// Empty reports whether the configs have the same coins and settings
+func (diff CoinDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// DiffCoins compares the running coins with the next config's, matching them by currency code
+func DiffCoins(running []conf.CoinData, next []conf.CoinData) CoinDiff {
+
+	diff := CoinDiff{}
+
+	current := map[string]conf.CoinData{}
+
+	for _, coinData := range running {
+		current[coinData.CurrencyCode] = coinData
+	}
+
+	for _, coinData := range next {
+
+		from, ok := current[coinData.CurrencyCode]
+		delete(current, coinData.CurrencyCode)
+
+		if !ok {
+			diff.Added = append(diff.Added, coinData)
+			continue
+		}
+
+		if !reflect.DeepEqual(normalised(from), normalised(coinData)) {
+			diff.Changed = append(diff.Changed, CoinChange{
+				From:     from,
+				To:       coinData,
+				Relaunch: !reflect.DeepEqual(launchSettings(from), launchSettings(coinData)),
+			})
+		}
+
+	}
+
+	for _, coinData := range running {
+		if _, ok := current[coinData.CurrencyCode]; ok {
+			diff.Removed = append(diff.Removed, coinData)
+		}
+	}
+
+	return diff
+
+}
+
+// normalised returns the coin with no daemon options treated the same as empty ones
+func normalised(coinData conf.CoinData) conf.CoinData {
+
+	if len(coinData.DaemonOptions) == 0 {
+		coinData.DaemonOptions = nil
+	}
+
+	return coinData
+
+}
+
+// launchSettings are the coin settings its daemon is launched with
+func launchSettings(coinData conf.CoinData) []interface{} {
+
+	coinData = normalised(coinData)
+
+	return []interface{}{
+		coinData.LibPath,
+		coinData.DataDir,
+		coinData.DaemonVersion,
+		coinData.WindowsDaemonName,
+		coinData.DarwinDaemonName,
+		coinData.UseTestNet,
+		coinData.IndexTransactions,
+		coinData.DaemonPath,
+		coinData.DaemonOptions,
+	}
+
+}
+
+// ReloadAppConfig reads the app config at path and applies how its coins differ from
+// the running ones, an invalid config is rejected and the running one kept
+func ReloadAppConfig(handler *Handler, path string) error {
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := conf.ReadAppConfig(path)
+
+	if err != nil {
+		return err
+	}
+
+	// diffing against the loaded config means our own saves, pins and upgrades, are no change
+	diff := DiffCoins(conf.ActiveCoins(), next.Coins)
+
+	if diff.Empty() {
+		return nil
+	}
+
+	log.Printf("reloading the app config: %d coin(s) added, %d removed, %d changed", len(diff.Added), len(diff.Removed), len(diff.Changed))
+
+	conf.SetAppConfig(next)
+
+	applyCoinDiff(diff)
+
+	restartListServices(conf.CurrentServerConfig(), next.Coins)
+
+	handler.Swap(NewRouter(next.Coins))
+
+	return nil
+
+}
+
+// applyCoinDiff stops the services of removed and changed coins and starts the new ones
+func applyCoinDiff(diff CoinDiff) {
+
+	started := []conf.CoinData{}
+
+	for _, coinData := range diff.Removed {
+
+		stopCoinServices(coinData.CurrencyCode)
+
+		coinData := coinData
+		queueDaemonOp(coinData.CurrencyCode, func() {
+			logStopErr(coinData, stopDaemon(coinData, daemonStopTimeout))
+		})
+
+	}
+
+	for _, change := range diff.Changed {
+
+		stopCoinServices(change.From.CurrencyCode)
+		started = append(started, change.To)
+
+		if !change.Relaunch {
+			continue
+		}
+
+		// the running daemon is stopped with the settings it was launched with
+		change := change
+		queueDaemonOp(change.From.CurrencyCode, func() {
+			logStopErr(change.From, stopDaemon(change.From, daemonStopTimeout))
+			startDaemon(change.To)
+		})
+
+	}
+
+	for _, coinData := range diff.Added {
+		started = append(started, coinData)
+		coinData := coinData
+		queueDaemonOp(coinData.CurrencyCode, func() { startDaemon(coinData) })
+	}
+
+	if err := StartHistoryStores(started); err != nil {
+		log.Println("Failed to start the address history stores: " + err.Error())
+	}
+
+	StartHubs(started)
+
+}
+
+// logStopErr logs a failure to stop the coin's daemon, one that wasn't running is fine
+func logStopErr(coinData conf.CoinData, err error) {
+	if err != nil && err != daemon.ErrNotRunning {
+		log.Println("Failed to stop the " + coinData.CurrencyCode + " daemon: " + err.Error())
+	}
+}
+
+// ReloadServerConfig reads the server config at path and applies it, an invalid
+// config is rejected and settings that are only read at startup keep their values
+func ReloadServerConfig(handler *Handler, path string) error {
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := conf.ReadServerConfig(path)
+
+	if err != nil {
+		return err
+	}
+
+	current := conf.CurrentServerConfig()
+
+	// an empty token was generated at startup, keep it
+	if next.APIToken == "" {
+		next.APIToken = current.APIToken
+	}
+
+	if next.ManagerAPIPort != current.ManagerAPIPort ||
+		next.TxCacheSize != current.TxCacheSize ||
+		next.TxCacheMinConfirmations != current.TxCacheMinConfirmations ||
+		next.TxCachePersist != current.TxCachePersist {
+
+		log.Println("The managerApiPort and txCache settings only change on restart, keeping the running ones")
+
+		next.ManagerAPIPort = current.ManagerAPIPort
+		next.TxCacheSize = current.TxCacheSize
+		next.TxCacheMinConfirmations = current.TxCacheMinConfirmations
+		next.TxCachePersist = current.TxCachePersist
+
+	}
+
+	if reflect.DeepEqual(next, current) {
+		return nil
+	}
+
+	log.Println("reloading the server config")
+
+	conf.SetServerConfig(next)
+
+	coins := conf.ActiveCoins()
+
+	restartListServices(next, coins)
+
+	handler.Swap(NewRouter(coins))
+
+	return nil
+
+}
+
+// WatchConfigs reloads the app and server configs when their files change
+func WatchConfigs(handler *Handler, appPath string, serverPath string) error {
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	reloads := map[string]func(*Handler, string) error{
+		filepath.Clean(appPath):    ReloadAppConfig,
+		filepath.Clean(serverPath): ReloadServerConfig,
+	}
+
+	// editors often replace a file rather than write it, so the directories are watched
+	for path := range reloads {
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	go watchConfigs(watcher, handler, reloads)
+
+	return nil
+
+}
+
+// watchConfigs reloads a config once its file has been left alone for reloadDelay
+func watchConfigs(watcher *fsnotify.Watcher, handler *Handler, reloads map[string]func(*Handler, string) error) {
+
+	timers := map[string]*time.Timer{}
+
+	for {
+		select {
+
+		case event, ok := <-watcher.Events:
+
+			if !ok {
+				return
+			}
+
+			path := filepath.Clean(event.Name)
+			reload, watched := reloads[path]
+
+			if !watched || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			if timer, ok := timers[path]; ok {
+				timer.Stop()
+			}
+
+			timers[path] = time.AfterFunc(reloadDelay, func() {
+				if err := reload(handler, path); err != nil {
+					log.Println("Failed to reload " + path + ", keeping the running config: " + err.Error())
+				}
+			})
+
+		case err, ok := <-watcher.Errors:
+
+			if !ok {
+				return
+			}
+
+			log.Println("Failed to watch the configs: " + err.Error())
+
+		}
+	}
+
+}
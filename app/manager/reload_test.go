@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+// reloadCoin returns a coin that passes validation
+func reloadCoin(currency string, port int) conf.CoinData {
+	return conf.CoinData{
+		Name:             currency,
+		CurrencyCode:     currency,
+		LibPath:          "navcoin",
+		DataDir:          "/lib/" + currency,
+		DaemonVersion:    "4.2.1",
+		LatestReleaseAPI: "https://api.github.com/repos/NAVCoin/navcoin-core/releases/latest",
+		ReleaseAPI:       "https://api.github.com/repos/NAVCoin/navcoin-core/releases",
+		LivePort:         port,
+	}
+}
+
+func Test_DiffCoins(t *testing.T) {
+
+	nav := reloadCoin("NAV", 44444)
+	btc := reloadCoin("BTC", 8332)
+
+	diff := DiffCoins([]conf.CoinData{nav}, []conf.CoinData{nav})
+	assert.True(t, diff.Empty(), "the same coins are no change")
+
+	withOptions := nav
+	withOptions.DaemonOptions = map[string]interface{}{}
+	assert.True(t, DiffCoins([]conf.CoinData{nav}, []conf.CoinData{withOptions}).Empty(), "empty daemon options are the same as none")
+
+	diff = DiffCoins([]conf.CoinData{nav}, []conf.CoinData{btc})
+	assert.Equal(t, []conf.CoinData{btc}, diff.Added)
+	assert.Equal(t, []conf.CoinData{nav}, diff.Removed)
+	assert.Empty(t, diff.Changed)
+
+	heartbeat := nav
+	heartbeat.DaemonHeartbeat = 60000
+	diff = DiffCoins([]conf.CoinData{nav}, []conf.CoinData{heartbeat})
+	assert.Equal(t, []CoinChange{{From: nav, To: heartbeat, Relaunch: false}}, diff.Changed, "the heartbeat doesn't relaunch the daemon")
+
+	testnet := nav
+	testnet.UseTestNet = true
+	diff = DiffCoins([]conf.CoinData{nav}, []conf.CoinData{testnet})
+	assert.Equal(t, []CoinChange{{From: nav, To: testnet, Relaunch: true}}, diff.Changed, "switching network relaunches the daemon")
+
+	options := nav
+	options.DaemonOptions = map[string]interface{}{"dbcache": 450}
+	diff = DiffCoins([]conf.CoinData{nav}, []conf.CoinData{options})
+	assert.True(t, diff.Changed[0].Relaunch, "daemon options relaunch the daemon")
+
+}
+
+func Test_HandlerSwap(t *testing.T) {
+
+	handler := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	handler.Swap(http.NotFoundHandler())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "requests after a swap are served by the new router")
+
+}
+
+func Test_ReloadAppConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	running := conf.AppConfig{Coins: []conf.CoinData{reloadCoin("NAV", 44444)}}
+
+	appConf := conf.AppConf
+	defer func() { conf.AppConf = appConf }()
+	conf.AppConf = running
+
+	stop, start := stopDaemon, startDaemon
+	defer func() { stopDaemon, startDaemon = stop, start }()
+
+	started := 0
+	startDaemon = func(coinData conf.CoinData) { started++ }
+
+	handler := NewHandler(http.NotFoundHandler())
+	path := filepath.Join(dir, "app-config.json")
+
+	// an invalid config is rejected and the running one kept
+	ioutil.WriteFile(path, []byte(`{"coins": [{"name": "NavCoin", "currencyCode": "NAV", "livePort": 44444}]}`), 0644)
+
+	err = ReloadAppConfig(handler, path)
+	_, invalid := err.(conf.ConfigErrors)
+	assert.True(t, invalid, "the invalid config's problems are returned")
+	assert.Equal(t, running, conf.AppConf)
+	assert.Equal(t, 0, started)
+
+	// the running config written back is no change
+	ioutil.WriteFile(path, []byte(`{"coins": [{
+		"name": "NAV",
+		"currencyCode": "NAV",
+		"libPath": "navcoin",
+		"dataDir": "/lib/NAV",
+		"daemonVersion": "4.2.1",
+		"latestReleaseApi": "https://api.github.com/repos/NAVCoin/navcoin-core/releases/latest",
+		"ReleaseApi": "https://api.github.com/repos/NAVCoin/navcoin-core/releases",
+		"livePort": 44444,
+		"daemonOptions": {}
+	}]}`), 0644)
+
+	assert.Nil(t, ReloadAppConfig(handler, path))
+	assert.Equal(t, running, conf.AppConf)
+	assert.Equal(t, 0, started)
+
+}
+
+func Test_queueDaemonOp(t *testing.T) {
+
+	stopping := make(chan struct{})
+	events := make(chan string, 3)
+
+	// a removed coin's daemon is still stopping when the coin is added back
+	queueDaemonOp("QUEUE", func() {
+		<-stopping
+		events <- "stop"
+	})
+	queueDaemonOp("QUEUE", func() { events <- "start" })
+
+	// other currencies aren't held up
+	queueDaemonOp("OTHER", func() { events <- "other" })
+	assert.Equal(t, "other", <-events)
+
+	select {
+	case event := <-events:
+		t.Errorf("%s ran before the stop finished", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(stopping)
+
+	assert.Equal(t, "stop", <-events)
+	assert.Equal(t, "start", <-events)
+
+}
+
+func Test_ReloadServerConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	serverConf := conf.CurrentServerConfig()
+	defer conf.SetServerConfig(serverConf)
+
+	running := conf.ServerConfig{ManagerAPIPort: 9002, APIToken: "token", BackupKeep: 10}
+	conf.SetServerConfig(running)
+
+	path := filepath.Join(dir, "server-config.json")
+
+	// an invalid config is rejected and the running one kept
+	ioutil.WriteFile(path, []byte(`{"managerApiPort": 9002, "backupInterval": -1, "backupKeep": -5}`), 0644)
+
+	err = ReloadServerConfig(NewHandler(http.NotFoundHandler()), path)
+	_, invalid := err.(conf.ConfigErrors)
+	assert.True(t, invalid, "the invalid config's problems are returned")
+	assert.Equal(t, running, conf.CurrentServerConfig())
+
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+)
+
+// unauthorizedResp matches the api error envelope for the UNAUTHORIZED code
+const unauthorizedResp = `{"errors":[{"code":"UNAUTHORIZED","errorMessage":"A valid API token is required"}]}`
+
+// AuthHandler only lets requests through that carry the server's API token
+// either as an "Authorization: Bearer <token>" header or a ?token= query param
+// the query param allows browser WebSocket clients, which can't set headers
+func AuthHandler() Adapter {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+			if token == "" {
+				token = r.URL.Query().Get("token")
+			}
+
+			apiToken := conf.CurrentServerConfig().APIToken
+
+			if apiToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(unauthorizedResp))
+				return
+			}
+
+			// all good continue
+			h.ServeHTTP(w, r)
+
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/utils"
+)
+
+// Webhook events sent to callback URLs
+const (
+	EventTxSeen      = "tx.seen"
+	EventTxConfirmed = "tx.confirmed"
+)
+
+// Headers sent with every webhook POST
+const (
+	HeaderSignature = "X-Kauri-Signature"
+	HeaderEvent     = "X-Kauri-Event"
+	HeaderDelivery  = "X-Kauri-Delivery"
+)
+
+// maxAttempts is the number of times a delivery is tried
+var maxAttempts = 5
+
+// retryBackoff is the delay before the first retry, doubled for each retry after
+var retryBackoff = 2 * time.Second
+
+// maxDeliveryLog is the number of deliveries kept in the log
+const maxDeliveryLog = 500
+
+// client posts webhooks with its own transport and a timeout
+var client = &http.Client{Transport: &http.Transport{}, Timeout: 15 * time.Second}
+
+// Payload is the json body posted to the callback URL
+type Payload struct {
+	DeliveryID    string `json:"deliveryId"`
+	WatchID       string `json:"watchId"`
+	Event         string `json:"event"`
+	Currency      string `json:"currency"`
+	Address       string `json:"address"`
+	TxID          string `json:"txid"`
+	Confirmations int64  `json:"confirmations"`
+	Depth         int64  `json:"depth"`
+}
+
+// Delivery is a delivery log entry
+type Delivery struct {
+	Payload
+	CallbackURL string    `json:"callbackUrl"`
+	Attempts    int       `json:"attempts"`
+	Status      int       `json:"status"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// deliveryLog keeps the most recent deliveries
+var deliveryLog = struct {
+	sync.Mutex
+	entries []*Delivery
+}{}
+
+// Sign returns the signature header value for the body - the hex
+// HMAC-SHA256 of the exact body using the watch's secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliveries returns a copy of the delivery log, newest first
+// only deliveries for watchID are returned if it is set
+func Deliveries(watchID string) []Delivery {
+
+	deliveryLog.Lock()
+	defer deliveryLog.Unlock()
+
+	deliveries := []Delivery{}
+
+	for i := len(deliveryLog.entries) - 1; i >= 0; i-- {
+		entry := deliveryLog.entries[i]
+		if watchID == "" || entry.WatchID == watchID {
+			deliveries = append(deliveries, *entry)
+		}
+	}
+
+	return deliveries
+
+}
+
+// deliver posts the payload to the watch's callback URL, retrying with
+// exponential backoff until it is accepted or maxAttempts is reached
+func deliver(watch Watch, payload Payload) *Delivery {
+
+	payload.DeliveryID, _ = utils.GenerateRandomString(12)
+
+	delivery := &Delivery{Payload: payload, CallbackURL: watch.CallbackURL, UpdatedAt: time.Now().UTC()}
+	logDelivery(delivery)
+
+	body, _ := json.Marshal(payload)
+	backoff := retryBackoff
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+
+		status, err := post(watch, payload, body)
+
+		deliveryLog.Lock()
+		delivery.Attempts = attempt
+		delivery.Status = status
+		delivery.Success = err == nil
+		delivery.Error = ""
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		delivery.UpdatedAt = time.Now().UTC()
+		deliveryLog.Unlock()
+
+		if err == nil {
+			return delivery
+		}
+
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+	}
+
+	log.Println("Giving up on webhook delivery " + payload.DeliveryID + " to " + watch.CallbackURL)
+
+	return delivery
+
+}
+
+// post sends a single signed delivery attempt
+func post(watch Watch, payload Payload, body []byte) (int, error) {
+
+	req, err := http.NewRequest(http.MethodPost, watch.CallbackURL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(watch.Secret, body))
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.DeliveryID)
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+
+}
+
+// logDelivery adds the delivery to the log, dropping the oldest entries
+func logDelivery(delivery *Delivery) {
+
+	deliveryLog.Lock()
+	defer deliveryLog.Unlock()
+
+	deliveryLog.entries = append(deliveryLog.entries, delivery)
+
+	if len(deliveryLog.entries) > maxDeliveryLog {
+		deliveryLog.entries = deliveryLog.entries[len(deliveryLog.entries)-maxDeliveryLog:]
+	}
+
+}
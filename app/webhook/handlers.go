@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Encrypt-S/kauri-api/app/address"
+	"github.com/Encrypt-S/kauri-api/app/api"
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+	"github.com/gorilla/mux"
+)
+
+// IncomingWatch is the request body for creating a watch
+type IncomingWatch struct {
+	Currency    string  `json:"currency"`
+	Address     string  `json:"address"`
+	CallbackURL string  `json:"callbackUrl"`
+	Depths      []int64 `json:"depths"`
+}
+
+// InitWebhookHandlers sets up the protected watch and delivery log endpoints
+func InitWebhookHandlers(r *mux.Router, store *Store, coins []conf.CoinData, prefix string) {
+
+	namespace := "webhooks"
+
+	// watches endpoint :: lists watches, or creates one returning its signing secret
+	watchesPath := api.RouteBuilder(prefix, namespace, "v1", "watches")
+	api.ProtectedRouteHandler(watchesPath, r, listWatchesHandler(store), http.MethodGet)
+	api.ProtectedRouteHandler(watchesPath, r, createWatchHandler(store, coins), http.MethodPost)
+
+	// watch endpoint :: deletes the watch
+	watchPath := api.RouteBuilder(prefix, namespace, "v1", "watches/{id}")
+	api.ProtectedRouteHandler(watchPath, r, deleteWatchHandler(store), http.MethodDelete)
+
+	// deliveries endpoint :: the delivery log, optionally for a single ?watch=
+	deliveriesPath := api.RouteBuilder(prefix, namespace, "v1", "deliveries")
+	api.ProtectedRouteHandler(deliveriesPath, r, deliveriesHandler(), http.MethodGet)
+
+}
+
+// listWatchesHandler returns the watches without their secrets
+func listWatchesHandler(store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiResp := api.Response{}
+		apiResp.Data = store.List()
+		apiResp.Send(w)
+	})
+}
+
+// createWatchHandler validates the incoming watch and adds it to the store
+// the response is the only time the watch's secret is returned
+func createWatchHandler(store *Store, coins []conf.CoinData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		incoming := IncomingWatch{}
+
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+			returnErr := api.AppRespErrors.JSONDecodeError
+			returnErr.ErrorMessage = fmt.Sprintf("JSON decode error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		coinData, ok := activeCoin(coins, incoming.Currency)
+
+		if !ok {
+			apiResp.Errors = append(apiResp.Errors, api.AppRespErrors.UnsupportedCoin)
+			apiResp.Send(w)
+			return
+		}
+
+		if err := validateWatch(coinData, incoming); err != nil {
+			returnErr := api.AppRespErrors.InvalidRequest
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		// the address's txs in existing blocks aren't called back
+		var height int64
+
+		if err := daemonrpc.CallDaemon(coinData, "getblockcount", nil, &height); err != nil {
+			returnErr := api.AppRespErrors.RPCResponseError
+			returnErr.ErrorMessage = fmt.Sprintf("Block count error: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		watch, err := store.Add(Watch{
+			Currency:    coinData.CurrencyCode,
+			Address:     incoming.Address,
+			CallbackURL: incoming.CallbackURL,
+			Depths:      incoming.Depths,
+		}, height)
+
+		if err != nil {
+			returnErr := api.AppRespErrors.ServerError
+			returnErr.ErrorMessage = fmt.Sprintf("Failed to save watch: %v", err)
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		watch.Delivered = nil
+		watch.Settled = 0
+		apiResp.Data = watch
+		apiResp.Send(w)
+
+	})
+}
+
+// deleteWatchHandler removes the watch with the {id}
+func deleteWatchHandler(store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		apiResp := api.Response{}
+
+		id := mux.Vars(r)["id"]
+
+		if err := store.Remove(id); err != nil {
+			returnErr := api.AppRespErrors.ServerError
+			if err == ErrWatchNotFound {
+				returnErr = api.AppRespErrors.NotFound
+			}
+			returnErr.ErrorMessage = err.Error()
+			apiResp.Errors = append(apiResp.Errors, returnErr)
+			apiResp.Send(w)
+			return
+		}
+
+		apiResp.Data = id
+		apiResp.Send(w)
+
+	})
+}
+
+// deliveriesHandler returns the delivery log, newest first
+func deliveriesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiResp := api.Response{}
+		apiResp.Data = Deliveries(r.URL.Query().Get("watch"))
+		apiResp.Send(w)
+	})
+}
+
+// validateWatch checks the address is valid for the coin, the callback is
+// an absolute http(s) URL and the confirmation depths are positive
+func validateWatch(coinData conf.CoinData, incoming IncomingWatch) error {
+
+	if incoming.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	if address.IsSupported(coinData.CurrencyCode) {
+		if err := address.Validate(coinData.CurrencyCode, incoming.Address, coinData.UseTestNet); err != nil {
+			return err
+		}
+	}
+
+	callback, err := url.Parse(incoming.CallbackURL)
+
+	if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+		return fmt.Errorf("callbackUrl must be an absolute http or https URL")
+	}
+
+	for _, depth := range incoming.Depths {
+		if depth < 1 {
+			return fmt.Errorf("depths must be at least 1 confirmation")
+		}
+	}
+
+	return nil
+
+}
+
+// activeCoin returns the active coin with the currency code
+func activeCoin(coins []conf.CoinData, currency string) (conf.CoinData, bool) {
+	for _, coinData := range coins {
+		if coinData.CurrencyCode == currency {
+			return coinData, true
+		}
+	}
+	return conf.CoinData{}, false
+}
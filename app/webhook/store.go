@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/utils"
+)
+
+// DefaultDepths are the confirmation depths notified when a watch doesn't set its own
+var DefaultDepths = []int64{1, 6}
+
+// ErrWatchNotFound is returned when no watch has the requested id
+var ErrWatchNotFound = errors.New("watch not found")
+
+// Watch is a persisted request to call back CallbackURL when a transaction
+// hits Address, and again as it reaches each of the confirmation Depths
+// Delivered records the depths already sent for each recent txid, depth 0
+// being the first sighting of the transaction, and every transaction in a
+// block at or below Settled has had all of its depths sent
+type Watch struct {
+	ID          string             `json:"id"`
+	Currency    string             `json:"currency"`
+	Address     string             `json:"address"`
+	CallbackURL string             `json:"callbackUrl"`
+	Secret      string             `json:"secret,omitempty"`
+	Depths      []int64            `json:"depths"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	Delivered   map[string][]int64 `json:"delivered,omitempty"`
+	Settled     int64              `json:"settled,omitempty"`
+}
+
+// Store is the persisted watch list
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	watches map[string]*Watch
+}
+
+// NewStore creates a store persisted to the json file at path
+func NewStore(path string) *Store {
+	return &Store{path: path, watches: make(map[string]*Watch)}
+}
+
+// Load reads the watch list from disk, an absent file is an empty list
+func (s *Store) Load() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := ioutil.ReadFile(s.path)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	watches := []*Watch{}
+
+	if err := json.Unmarshal(data, &watches); err != nil {
+		return err
+	}
+
+	for _, watch := range watches {
+		if watch.Delivered == nil {
+			watch.Delivered = make(map[string][]int64)
+		}
+		s.watches[watch.ID] = watch
+	}
+
+	return nil
+
+}
+
+// Add creates a watch with a new id and signing secret
+// the watch is settled at the chain height so only
+// transactions in later blocks are called back
+func (s *Store) Add(watch Watch, height int64) (Watch, error) {
+
+	var err error
+
+	if watch.ID, err = utils.GenerateRandomString(12); err != nil {
+		return Watch{}, err
+	}
+
+	if watch.Secret, err = utils.GenerateRandomString(32); err != nil {
+		return Watch{}, err
+	}
+
+	if len(watch.Depths) == 0 {
+		watch.Depths = DefaultDepths
+	}
+
+	sort.Slice(watch.Depths, func(i, j int) bool { return watch.Depths[i] < watch.Depths[j] })
+
+	watch.CreatedAt = time.Now().UTC()
+	watch.Delivered = make(map[string][]int64)
+	watch.Settled = height
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.watches[watch.ID] = &watch
+
+	return watch, s.save()
+
+}
+
+// Remove deletes the watch with the id
+func (s *Store) Remove(id string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.watches[id]; !ok {
+		return ErrWatchNotFound
+	}
+
+	delete(s.watches, id)
+
+	return s.save()
+
+}
+
+// List returns copies of the watches, without their secrets or delivery state
+func (s *Store) List() []Watch {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watches := []Watch{}
+
+	for _, watch := range s.watches {
+		w := *watch
+		w.Secret = ""
+		w.Delivered = nil
+		w.Settled = 0
+		watches = append(watches, w)
+	}
+
+	sort.Slice(watches, func(i, j int) bool { return watches[i].CreatedAt.Before(watches[j].CreatedAt) })
+
+	return watches
+
+}
+
+// forCurrency returns copies of the currency's watches including their secrets
+func (s *Store) forCurrency(currency string) []Watch {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watches := []Watch{}
+
+	for _, watch := range s.watches {
+		if watch.Currency == currency {
+			watches = append(watches, *watch)
+		}
+	}
+
+	return watches
+
+}
+
+// pendingDepths returns the depths not yet delivered for the watch's txid
+func (s *Store) pendingDepths(id string, txID string) []int64 {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watch, ok := s.watches[id]
+
+	if !ok {
+		return nil
+	}
+
+	delivered := make(map[int64]bool)
+	for _, depth := range watch.Delivered[txID] {
+		delivered[depth] = true
+	}
+
+	pending := []int64{}
+	for _, depth := range append([]int64{0}, watch.Depths...) {
+		if !delivered[depth] {
+			pending = append(pending, depth)
+		}
+	}
+
+	return pending
+
+}
+
+// markDelivered records that the depth has been sent for the watch's txid
+func (s *Store) markDelivered(id string, txID string, depth int64) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watch, ok := s.watches[id]
+
+	if !ok {
+		return ErrWatchNotFound
+	}
+
+	watch.Delivered[txID] = append(watch.Delivered[txID], depth)
+
+	return s.save()
+
+}
+
+// settle moves the watch's settled height up to height and forgets the
+// delivered txids not in seen, the txids the daemon returned above the
+// previous settled height and in the mempool, which have either had every
+// depth delivered or dropped out of the chain
+func (s *Store) settle(id string, height int64, seen map[string]bool) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watch, ok := s.watches[id]
+
+	if !ok {
+		return ErrWatchNotFound
+	}
+
+	if height > watch.Settled {
+		watch.Settled = height
+	}
+
+	for txID := range watch.Delivered {
+		if !seen[txID] {
+			delete(watch.Delivered, txID)
+		}
+	}
+
+	return s.save()
+
+}
+
+// save writes the watch list to disk, the lock must be held
+func (s *Store) save() error {
+
+	watches := []*Watch{}
+	for _, watch := range s.watches {
+		watches = append(watches, watch)
+	}
+
+	data, err := json.MarshalIndent(watches, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	// write then rename so a crash never leaves a half written list
+	tmp := s.path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"log"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonevents"
+	"github.com/Encrypt-S/kauri-api/app/daemon/daemonrpc"
+)
+
+// pollInterval is how often watches are checked without a daemon notification
+var pollInterval = 30 * time.Second
+
+// addressParams are the params for the address index RPC calls
+type addressParams struct {
+	Addresses []string `json:"addresses"`
+}
+
+// rangeParams are the params for the address index RPC calls limited to a range of blocks
+type rangeParams struct {
+	Addresses []string `json:"addresses"`
+	Start     int64    `json:"start"`
+	End       int64    `json:"end"`
+}
+
+// mempoolEntry is the part of a 'getaddressmempool' entry the watcher needs
+type mempoolEntry struct {
+	TxID string `json:"txid"`
+}
+
+// txConfirmations is the part of a verbose 'getrawtransaction' the watcher needs
+type txConfirmations struct {
+	Confirmations int64 `json:"confirmations"`
+}
+
+// Watcher checks the watched addresses of the active coins and
+// delivers webhooks as their transactions are seen and confirmed
+type Watcher struct {
+	store *Store
+	coins []conf.CoinData
+}
+
+// NewWatcher creates a watcher for the store's watches on the coins
+func NewWatcher(store *Store, coins []conf.CoinData) *Watcher {
+	return &Watcher{store: store, coins: coins}
+}
+
+// Run checks the watches whenever a daemon notifies us of a new block
+// or transaction, polling as a fallback, until stop is closed
+// transactions are coalesced so a burst of them costs one check
+func (watcher *Watcher) Run(stop <-chan struct{}) {
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	events := daemonevents.Events.Subscribe()
+	defer daemonevents.Events.Unsubscribe(events)
+
+	coalesced := daemonevents.Coalesce(events)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case ev := <-coalesced:
+			for _, coinData := range watcher.coins {
+				if coinData.CurrencyCode == ev.Currency {
+					watcher.scan(coinData)
+				}
+			}
+		case <-ticker.C:
+			for _, coinData := range watcher.coins {
+				watcher.scan(coinData)
+			}
+		}
+	}
+
+}
+
+// scan checks each of the coin's watches
+func (watcher *Watcher) scan(coinData conf.CoinData) {
+	for _, watch := range watcher.store.forCurrency(coinData.CurrencyCode) {
+		if err := watcher.check(coinData, watch); err != nil {
+			log.Println("Failed to check " + coinData.CurrencyCode + " webhook watch " + watch.ID + ": " + err.Error())
+		}
+	}
+}
+
+// check queues a delivery for every depth the watch's transactions have
+// newly reached, depth 0 being the first sighting in the mempool or a block
+// only blocks above the watch's settled height are looked at, and once the
+// deepest depth is delivered for every transaction up to a height it is settled
+func (watcher *Watcher) check(coinData conf.CoinData, watch Watch) error {
+
+	// the mempool is read before the height so a tx mined
+	// in between is in the blocks listed below
+	unconfirmed, err := mempoolTxIDs(coinData, watch.Address)
+
+	if err != nil {
+		return err
+	}
+
+	var height int64
+
+	if err := daemonrpc.CallDaemon(coinData, "getblockcount", nil, &height); err != nil {
+		return err
+	}
+
+	confirmed := []string{}
+
+	if height > watch.Settled {
+		if confirmed, err = confirmedTxIDs(coinData, watch.Address, watch.Settled+1, height); err != nil {
+			return err
+		}
+	}
+
+	seen := make(map[string]bool)
+
+	for _, txID := range unconfirmed {
+		seen[txID] = true
+		if err := watcher.notify(watch, txID, 0); err != nil {
+			return err
+		}
+	}
+
+	for _, txID := range confirmed {
+
+		seen[txID] = true
+
+		// txs that have had every depth delivered aren't looked up again
+		if len(watcher.store.pendingDepths(watch.ID, txID)) == 0 {
+			continue
+		}
+
+		tx := txConfirmations{}
+
+		if err := daemonrpc.CallDaemon(coinData, "getrawtransaction", []interface{}{txID, 1}, &tx); err != nil {
+			return err
+		}
+
+		if err := watcher.notify(watch, txID, tx.Confirmations); err != nil {
+			return err
+		}
+
+	}
+
+	// every tx at or below this height has reached the deepest depth
+	settled := height - watch.Depths[len(watch.Depths)-1] + 1
+
+	return watcher.store.settle(watch.ID, settled, seen)
+
+}
+
+// notify records and delivers each pending depth the tx has reached
+func (watcher *Watcher) notify(watch Watch, txID string, confirmations int64) error {
+
+	for _, depth := range watcher.store.pendingDepths(watch.ID, txID) {
+
+		if depth > confirmations {
+			continue
+		}
+
+		// recorded before delivery so a slow callback isn't sent twice
+		if err := watcher.store.markDelivered(watch.ID, txID, depth); err != nil {
+			return err
+		}
+
+		event := EventTxConfirmed
+		if depth == 0 {
+			event = EventTxSeen
+		}
+
+		go deliver(watch, Payload{
+			WatchID:       watch.ID,
+			Event:         event,
+			Currency:      watch.Currency,
+			Address:       watch.Address,
+			TxID:          txID,
+			Confirmations: confirmations,
+			Depth:         depth,
+		})
+
+	}
+
+	return nil
+
+}
+
+// confirmedTxIDs returns the address's txids in the blocks from start to end
+func confirmedTxIDs(coinData conf.CoinData, address string, start int64, end int64) ([]string, error) {
+
+	params := []interface{}{rangeParams{Addresses: []string{address}, Start: start, End: end}}
+
+	confirmed := []string{}
+
+	if err := daemonrpc.CallDaemon(coinData, "getaddresstxids", params, &confirmed); err != nil {
+		return nil, err
+	}
+
+	return confirmed, nil
+
+}
+
+// mempoolTxIDs returns the address's mempool txids
+func mempoolTxIDs(coinData conf.CoinData, address string) ([]string, error) {
+
+	params := []interface{}{addressParams{Addresses: []string{address}}}
+
+	entries := []mempoolEntry{}
+
+	if err := daemonrpc.CallDaemon(coinData, "getaddressmempool", params, &entries); err != nil {
+		return nil, err
+	}
+
+	unconfirmed := []string{}
+	for _, entry := range entries {
+		unconfirmed = append(unconfirmed, entry.TxID)
+	}
+
+	return unconfirmed, nil
+
+}
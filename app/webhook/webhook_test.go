@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Encrypt-S/kauri-api/app/conf"
+	"github.com/Encrypt-S/kauri-api/app/internal/rpctest"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/jarcoal/httpmock.v1"
+)
+
+const testAddress = "NW7uXr4ZAeJKigMGnKbSLfCBQY59cH1T8G"
+
+// receiver is a callback server recording the payloads it accepts
+// it fails the first failures requests it is sent
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	payloads []Payload
+	verified bool
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	rc.verified = r.Header.Get(HeaderSignature) == Sign("secret", body)
+
+	payload := Payload{}
+	json.Unmarshal(body, &payload)
+	rc.payloads = append(rc.payloads, payload)
+
+}
+
+func (rc *receiver) received() []Payload {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Payload{}, rc.payloads...)
+}
+
+// newTestStore creates a store in a temporary directory
+func newTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "webhook")
+	assert.Nil(t, err)
+	return NewStore(filepath.Join(dir, "data", "webhooks.json")), func() { os.RemoveAll(dir) }
+}
+
+// test that the signature is the HMAC-SHA256 of the body
+func Test_Sign(t *testing.T) {
+	assert.Equal(t, "sha256=dc46983557fea127b43af721467eb9b3fde2338fe3e14f51952aa8478c13d355", Sign("secret", []byte("body")))
+	assert.NotEqual(t, Sign("secret", []byte("body")), Sign("other", []byte("body")))
+}
+
+// test that failed deliveries are retried until accepted
+func Test_deliver(t *testing.T) {
+
+	retryBackoff = time.Millisecond
+
+	rc := &receiver{failures: 2}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	watch := Watch{ID: "w1", CallbackURL: server.URL, Secret: "secret"}
+	delivery := deliver(watch, Payload{WatchID: "w1", Event: EventTxSeen, TxID: "t1"})
+
+	assert.True(t, delivery.Success)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.Status)
+	assert.True(t, rc.verified)
+	assert.Equal(t, "t1", rc.received()[0].TxID)
+
+	assert.Equal(t, delivery.DeliveryID, Deliveries("w1")[0].DeliveryID)
+	assert.Equal(t, 0, len(Deliveries("other")))
+
+}
+
+// test that watches persist and are settled at the height they are added
+func Test_Store(t *testing.T) {
+
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	watch, err := store.Add(Watch{Currency: "NAV", Address: testAddress, CallbackURL: "http://localhost", Depths: []int64{6, 1}}, 100)
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", watch.Secret)
+	assert.Equal(t, []int64{1, 6}, watch.Depths)
+	assert.Equal(t, int64(100), watch.Settled)
+	assert.Equal(t, []int64{0, 1, 6}, store.pendingDepths(watch.ID, "new"))
+
+	loaded := NewStore(store.path)
+	assert.Nil(t, loaded.Load())
+
+	list := loaded.List()
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, "", list[0].Secret)
+	assert.Equal(t, int64(0), list[0].Settled)
+	assert.Equal(t, int64(100), loaded.forCurrency("NAV")[0].Settled)
+	assert.Equal(t, watch.Secret, loaded.forCurrency("NAV")[0].Secret)
+
+	assert.Nil(t, loaded.Remove(watch.ID))
+	assert.Equal(t, ErrWatchNotFound, loaded.Remove(watch.ID))
+
+}
+
+// test that transactions are called back when seen and at each depth,
+// and forgotten once every depth is delivered and their block is settled
+func Test_check(t *testing.T) {
+
+	retryBackoff = time.Millisecond
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	watch, _ := store.Add(Watch{Currency: "NAV", Address: testAddress, CallbackURL: server.URL, Depths: []int64{1, 6}}, 100)
+	watch.Secret = "secret"
+	store.watches[watch.ID].Secret = "secret"
+
+	watcher := NewWatcher(store, []conf.CoinData{rpctest.CoinData()})
+
+	// check is passed the watch as stored, as scan does
+	check := func() error {
+		return watcher.check(rpctest.CoinData(), store.forCurrency("NAV")[0])
+	}
+
+	responses := map[string]string{
+		"getblockcount":     `{"result": 100, "error": null}`,
+		"getaddresstxids":   `{"result": [], "error": null}`,
+		"getaddressmempool": `{"result": [{"txid": "t1"}], "error": null}`,
+	}
+	httpmock.RegisterResponder("POST", "http://127.0.0.1:0", rpctest.Responder(responses))
+
+	assert.Nil(t, check())
+
+	responses["getblockcount"] = `{"result": 101, "error": null}`
+	responses["getaddresstxids"] = `{"result": ["t1"], "error": null}`
+	responses["getaddressmempool"] = `{"result": [], "error": null}`
+	responses["getrawtransaction"] = `{"result": {"confirmations": 1}, "error": null}`
+
+	assert.Nil(t, check())
+
+	// nothing new has happened so nothing more is sent
+	assert.Nil(t, check())
+
+	assert.Equal(t, []int64{6}, store.pendingDepths(watch.ID, "t1"))
+
+	responses["getblockcount"] = `{"result": 106, "error": null}`
+	responses["getrawtransaction"] = `{"result": {"confirmations": 6}, "error": null}`
+
+	assert.Nil(t, check())
+	assert.Equal(t, int64(101), store.forCurrency("NAV")[0].Settled)
+
+	// the next blocks listed are above t1's so it is forgotten
+	responses["getblockcount"] = `{"result": 107, "error": null}`
+	responses["getaddresstxids"] = `{"result": [], "error": null}`
+
+	assert.Nil(t, check())
+	assert.Equal(t, int64(102), store.forCurrency("NAV")[0].Settled)
+	assert.Empty(t, store.forCurrency("NAV")[0].Delivered)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(rc.received()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	received := rc.received()
+	assert.Equal(t, 3, len(received))
+
+	depths := map[int64]string{}
+	for _, payload := range received {
+		depths[payload.Depth] = payload.Event
+	}
+
+	assert.Equal(t, map[int64]string{0: EventTxSeen, 1: EventTxConfirmed, 6: EventTxConfirmed}, depths)
+	assert.True(t, rc.verified)
+
+}